@@ -0,0 +1,156 @@
+package app
+
+import (
+	"github.com/AKSarav/KubeNodeUsage/v3/cmd/namespacemodel"
+	"github.com/AKSarav/KubeNodeUsage/v3/cmd/nodemodel"
+	"github.com/AKSarav/KubeNodeUsage/v3/cmd/podmodel"
+	"github.com/AKSarav/KubeNodeUsage/v3/cmd/volumemodel"
+	"github.com/AKSarav/KubeNodeUsage/v3/k8s"
+	"github.com/AKSarav/KubeNodeUsage/v3/utils"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// App is the Bubble Tea model switching between the views
+// Enter on the node list opens the pods of the selected node and Esc goes back to the nodes
+// the node view stays as it was, with its sort, cursor and scroll position
+type App struct {
+	args       *utils.Inputs
+	collector  *k8s.Collector
+	nodes      *nodemodel.NodeUsage           // nil when started with --pods, --volumes or --by
+	pods       *podmodel.PodUsage             // the pods of the selected node, or all of them with --pods
+	volumes    *volumemodel.VolumeUsage       // with --volumes
+	namespaces *namespacemodel.NamespaceUsage // with --by namespace
+	size       tea.WindowSizeMsg              // last window size, handed to the views opened later
+}
+
+// New creates the app starting with the view chosen by the --pods, --volumes and --by flags
+func New(args *utils.Inputs, collector *k8s.Collector) App {
+	app := App{args: args, collector: collector}
+	switch {
+	case args.By == "namespace":
+		namespaces := namespacemodel.NewNamespaceUsage(args, collector)
+		app.namespaces = &namespaces
+	case args.Pods:
+		pods := podmodel.NewPodUsage(args, collector)
+		app.pods = &pods
+	case args.Volumes:
+		volumes := volumemodel.NewVolumeUsage(args, collector)
+		app.volumes = &volumes
+	default:
+		nodes := nodemodel.NewNodeUsage(args, collector)
+		app.nodes = &nodes
+	}
+	return app
+}
+
+// active returns the view on screen
+func (a App) active() tea.Model {
+	switch {
+	case a.pods != nil:
+		return *a.pods
+	case a.volumes != nil:
+		return *a.volumes
+	case a.namespaces != nil:
+		return *a.namespaces
+	default:
+		return *a.nodes
+	}
+}
+
+// Init Bubble Tea app
+func (a App) Init() tea.Cmd {
+	return a.active().Init()
+}
+
+// Update routes the keys to the view on screen and everything else to all the views
+func (a App) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		a.size = msg
+	case tea.KeyMsg:
+		// Enter on the node list opens the pods of the selected node
+		if a.nodes != nil && a.pods == nil && msg.Type == tea.KeyEnter {
+			if node, ok := a.nodes.Selected(); ok {
+				return a.openPods(node.Name)
+			}
+			return a, nil
+		}
+
+		// Esc goes back to the node list unless it ends a search in the pods
+		if a.nodes != nil && a.pods != nil && msg.Type == tea.KeyEsc && !a.pods.Searching() {
+			a.pods = nil
+			return a, nil
+		}
+
+		return a.update(msg, true)
+	}
+
+	// Ticks and the like go to every view, each view only acts on its own
+	return a.update(msg, false)
+}
+
+// update passes the message to the active view only, or to all of them
+func (a App) update(msg tea.Msg, activeOnly bool) (tea.Model, tea.Cmd) {
+	var cmds []tea.Cmd
+
+	if a.pods != nil {
+		model, cmd := a.pods.Update(msg)
+		pods := model.(podmodel.PodUsage)
+		a.pods = &pods
+		cmds = append(cmds, cmd)
+		if activeOnly {
+			return a, tea.Batch(cmds...)
+		}
+	}
+	if a.volumes != nil {
+		model, cmd := a.volumes.Update(msg)
+		volumes := model.(volumemodel.VolumeUsage)
+		a.volumes = &volumes
+		cmds = append(cmds, cmd)
+		if activeOnly {
+			return a, tea.Batch(cmds...)
+		}
+	}
+	if a.namespaces != nil {
+		model, cmd := a.namespaces.Update(msg)
+		namespaces := model.(namespacemodel.NamespaceUsage)
+		a.namespaces = &namespaces
+		cmds = append(cmds, cmd)
+		if activeOnly {
+			return a, tea.Batch(cmds...)
+		}
+	}
+	if a.nodes != nil {
+		model, cmd := a.nodes.Update(msg)
+		nodes := model.(nodemodel.NodeUsage)
+		a.nodes = &nodes
+		cmds = append(cmds, cmd)
+	}
+	return a, tea.Batch(cmds...)
+}
+
+// openPods opens the pods of the node with inputs of their own
+// the node filters do not apply to pods and the combined view falls back to memory
+func (a App) openPods(node string) (tea.Model, tea.Cmd) {
+	args := *a.args
+	args.Pods = true
+	args.FilterNodes, args.FilterLabel, args.FilterColor = "", "", ""
+	args.DiskDetail = false
+	if args.Metrics == "all" {
+		args.Metrics = "memory"
+	}
+	_, args.SortBy = utils.SortMetric(a.args.SortBy, a.args.Metrics)
+
+	pods := podmodel.NewNodePodUsage(&args, a.collector, node)
+	model, cmd := pods.Update(a.size)
+	pods = model.(podmodel.PodUsage)
+	a.pods = &pods
+
+	return a, tea.Batch(cmd, pods.Init())
+}
+
+// View renders the view on screen
+func (a App) View() string {
+	return a.active().View()
+}
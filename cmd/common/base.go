@@ -0,0 +1,145 @@
+package common
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/AKSarav/KubeNodeUsage/v3/utils"
+
+	"github.com/charmbracelet/bubbles/progress"
+	"github.com/charmbracelet/bubbles/viewport"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Styles shared by the views
+var (
+	HelpStyle   = lipgloss.NewStyle().Foreground(lipgloss.Color("#626262")).Render
+	SearchStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("#FFFF00")).Bold(true)
+	ErrorStyle  = lipgloss.NewStyle().Foreground(lipgloss.Color("#F11658")).Bold(true)
+	CursorStyle = lipgloss.NewStyle().Reverse(true)
+	PaneStyle   = lipgloss.NewStyle().BorderStyle(lipgloss.NormalBorder()).BorderTop(true).BorderForeground(lipgloss.Color("#626262"))
+)
+
+// GetBar returns the usage bar for the usage ratio
+func GetBar(decider float64) progress.Model {
+	decider = decider * 100
+
+	var prog progress.Model
+	// decide which color to use based on the usage percentage below 30% is green, above 70% is red, else yellow
+	if decider < 30 {
+		prog = progress.New(progress.WithScaledGradient("#0bad5d", "#74b03f"))
+	} else if decider > 70 {
+		prog = progress.New(progress.WithScaledGradient("#13B013", "#F11658"))
+	} else {
+		prog = progress.New(progress.WithScaledGradient("#13B013", "#F18016"))
+	}
+	return prog
+}
+
+// Min is the minimum of two integers
+func Min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// Visible splits the content into the header and the row lines left after the search
+// and returns the rows of those lines in the same order, an empty search term keeps them all
+func Visible[T any](content string, rows []T, searchTerm string) (header []string, lines []string, visible []T) {
+	all := strings.Split(content, "\n")
+	start := utils.BodyStart(all)
+	header = all[:start]
+
+	searchTerm = strings.ToLower(searchTerm)
+	for i, line := range all[start:] {
+		if searchTerm != "" && !strings.Contains(strings.ToLower(line), searchTerm) {
+			continue
+		}
+		lines = append(lines, line)
+		if i < len(rows) {
+			visible = append(visible, rows[i])
+		}
+	}
+	return header, lines, visible
+}
+
+// ClampCursor keeps the cursor on one of the rows, the first one when there are none
+func ClampCursor(cursor, rows int) int {
+	if cursor >= rows {
+		cursor = rows - 1
+	}
+	if cursor < 0 {
+		cursor = 0
+	}
+	return cursor
+}
+
+// ScrollTo gives the viewport its height and scrolls it just enough to show the given line
+func ScrollTo(vp *viewport.Model, height, line int) {
+	vp.Height = height
+	if line < vp.YOffset {
+		vp.SetYOffset(line)
+	} else if line >= vp.YOffset+vp.Height {
+		vp.SetYOffset(line - vp.Height + 1)
+	}
+}
+
+// DetailPane renders the detail lines of the selected row under the table
+// it takes at most half of the screen, long label lists wrap within it
+func DetailPane(lines []string, width, height int) string {
+	return PaneStyle.Width(width).MaxHeight(height / 2).Render(strings.Join(lines, "\n"))
+}
+
+// BodyHeight is the height left to the viewport by the detail pane, the error banner and the help line
+func BodyHeight(height int, failed bool, pane string) int {
+	height--
+	if failed {
+		height--
+	}
+	if pane != "" {
+		height -= lipgloss.Height(pane)
+	}
+	if height < 1 {
+		height = 1
+	}
+	return height
+}
+
+// Table shifts the header and the rows by the horizontal scroll and highlights the row under the cursor
+func Table(header, lines []string, xOffset, cursor int) string {
+	var displayLines []string
+
+	// Always include the header lines - till the table header
+	for _, line := range header {
+		displayLines = append(displayLines, shift(line, xOffset))
+	}
+
+	// The rows left after the search, the selected one highlighted
+	for i, line := range lines {
+		line = shift(line, xOffset)
+		if i == cursor {
+			line = CursorStyle.Render(line)
+		}
+		displayLines = append(displayLines, line)
+	}
+	return strings.Join(displayLines, "\n")
+}
+
+// shift cuts the columns scrolled out on the left
+func shift(line string, xOffset int) string {
+	if len(line) > xOffset {
+		return line[xOffset:]
+	}
+	return ""
+}
+
+// Banner renders the error of a failed refresh with the delay before the next try, empty without error
+func Banner(err error, interval time.Duration, failures int, width int) string {
+	if err == nil {
+		return ""
+	}
+	return "\n" + ErrorStyle.MaxWidth(width).Render(fmt.Sprintf("Refresh failed, retrying in %s: %v",
+		utils.Backoff(interval, failures), err))
+}
@@ -0,0 +1,33 @@
+package exporter
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/AKSarav/KubeNodeUsage/v3/k8s"
+	"github.com/AKSarav/KubeNodeUsage/v3/utils"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Run serves the node usage (and the pod usage with --pods) as prometheus gauges on /metrics
+// The values are collected on every scrape with the same collector the TUI uses
+func Run(args *utils.Inputs, collector *k8s.Collector) error {
+	registry := prometheus.NewRegistry()
+	if err := registry.Register(NewUsageCollector(args, collector)); err != nil {
+		return err
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.HandlerFor(registry, promhttp.HandlerOpts{}))
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, "ok")
+	})
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "KubeNodeUsage %s exporter - metrics are served on /metrics\n", utils.Version)
+	})
+
+	utils.Logger.Info("Serving metrics on ", args.Exporter, "/metrics")
+	return http.ListenAndServe(args.Exporter, mux)
+}
@@ -0,0 +1,241 @@
+package exporter
+
+import (
+	"regexp"
+	"strconv"
+	"sync"
+
+	"github.com/AKSarav/KubeNodeUsage/v3/k8s"
+	"github.com/AKSarav/KubeNodeUsage/v3/utils"
+
+	"github.com/iancoleman/strcase"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const namespace = "kubenodeusage"
+
+const (
+	mib = 1024 * 1024
+	kib = 1024
+)
+
+var invalidLabelChars = regexp.MustCompile(`[^a-zA-Z0-9_]`)
+
+// UsageCollector is a prometheus.Collector that fetches the usage on every scrape
+type UsageCollector struct {
+	args       *utils.Inputs
+	collector  *k8s.Collector
+	mu         sync.Mutex // scrapes are serialized so the API is not hit concurrently
+	nodeKeys   []string
+	podKeys    []string
+	volumeKeys []string
+	up         *prometheus.Desc
+}
+
+// NewUsageCollector creates the prometheus collector
+// the --label column is added as an extra prometheus label named after its alias
+func NewUsageCollector(args *utils.Inputs, collector *k8s.Collector) *UsageCollector {
+	nodeKeys := []string{"node"}
+	podKeys := []string{"pod", "namespace", "node"}
+	volumeKeys := []string{"volume", "pod", "namespace", "node", "type", "pvc"}
+	if args.LabelToDisplay != "" {
+		labelName := invalidLabelChars.ReplaceAllString(strcase.ToSnake(args.LabelAlias), "_")
+		nodeKeys = append(nodeKeys, labelName)
+		podKeys = append(podKeys, labelName)
+		volumeKeys = append(volumeKeys, labelName)
+	}
+
+	return &UsageCollector{
+		args:       args,
+		collector:  collector,
+		nodeKeys:   nodeKeys,
+		podKeys:    podKeys,
+		volumeKeys: volumeKeys,
+		up:         prometheus.NewDesc(namespace+"_up", "Whether the last collection from the kubernetes API succeeded", nil, nil),
+	}
+}
+
+func (u *UsageCollector) nodeDesc(name string, help string) *prometheus.Desc {
+	return prometheus.NewDesc(prometheus.BuildFQName(namespace, "node", name), help, u.nodeKeys, nil)
+}
+
+func (u *UsageCollector) volumeDesc(name string, help string) *prometheus.Desc {
+	return prometheus.NewDesc(prometheus.BuildFQName(namespace, "volume", name), help, u.volumeKeys, nil)
+}
+
+func (u *UsageCollector) podDesc(name string, help string) *prometheus.Desc {
+	return prometheus.NewDesc(prometheus.BuildFQName(namespace, "pod", name), help, u.podKeys, nil)
+}
+
+// Describe sends no descriptors, which makes this an unchecked collector
+// the gauges depend on what the cluster returns and describing them by collecting
+// would hit the API on registration, failing the startup when it is slow or unreachable
+func (u *UsageCollector) Describe(ch chan<- *prometheus.Desc) {
+}
+
+// Collect fetches the nodes and pods and sends them as gauges
+func (u *UsageCollector) Collect(ch chan<- prometheus.Metric) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	up := 1.0
+
+	nodes, err := u.collector.Nodes()
+	if err != nil {
+		utils.Logger.Error("Failed to collect nodes: ", err)
+		up = 0
+	}
+	for _, node := range nodes {
+		u.collectNode(ch, node)
+	}
+
+	if u.args.Pods {
+		pods, err := u.collector.Pods()
+		if err != nil {
+			utils.Logger.Error("Failed to collect pods: ", err)
+			up = 0
+		}
+		for _, pod := range pods {
+			u.collectPod(ch, pod)
+		}
+	}
+
+	if u.args.Volumes {
+		volumes, err := u.collector.Volumes()
+		if err != nil {
+			utils.Logger.Error("Failed to collect volumes: ", err)
+			up = 0
+		}
+		for _, volume := range volumes {
+			u.collectVolume(ch, volume)
+		}
+	}
+
+	ch <- prometheus.MustNewConstMetric(u.up, prometheus.GaugeValue, up)
+}
+
+func (u *UsageCollector) collectNode(ch chan<- prometheus.Metric, node k8s.Node) {
+	labels := []string{node.Name}
+	if u.args.LabelToDisplay != "" {
+		labels = append(labels, node.LabelToDisplay)
+	}
+
+	gauge := func(name string, help string, value float64) {
+		ch <- prometheus.MustNewConstMetric(u.nodeDesc(name, help), prometheus.GaugeValue, value, labels...)
+	}
+
+	ready := 0.0
+	if node.Status == "Ready" {
+		ready = 1
+	}
+	gauge("ready", "Whether the node is Ready", ready)
+	if totalPods, err := strconv.Atoi(node.TotalPods); err == nil {
+		gauge("pods", "Number of pods scheduled on the node", float64(totalPods))
+	}
+
+	metric := u.args.Metrics
+	if metric == "memory" || metric == "all" {
+		gauge("memory_capacity_bytes", "Memory capacity of the node", float64(node.Capacity_memory)*kib)
+		gauge("memory_allocatable_bytes", "Memory allocatable on the node - the capacity minus the reserved resources", float64(node.Allocatable_memory)*kib)
+		gauge("memory_usage_bytes", "Memory used on the node", float64(node.Usage_memory)*kib)
+		gauge("memory_free_bytes", "Memory free on the node", float64(node.Free_memory)*kib)
+		gauge("memory_usage_percent", "Memory usage of the node in percent", float64(node.Usage_memory_percent))
+		gauge("memory_request_bytes", "Memory requested by the pods on the node", float64(node.Request_memory)*kib)
+		gauge("memory_limit_bytes", "Memory limits of the pods on the node", float64(node.Limit_memory)*kib)
+		gauge("memory_request_percent", "Memory requested by the pods against the allocatable memory", float64(node.Request_memory_percent))
+		gauge("memory_limit_percent", "Memory limits of the pods against the allocatable memory", float64(node.Limit_memory_percent))
+	}
+	if metric == "cpu" || metric == "all" {
+		gauge("cpu_capacity_cores", "CPU capacity of the node", float64(node.Capacity_cpu)/1000)
+		gauge("cpu_allocatable_cores", "CPU allocatable on the node - the capacity minus the reserved resources", float64(node.Allocatable_cpu)/1000)
+		gauge("cpu_usage_cores", "CPU used on the node", float64(node.Usage_cpu)/1000)
+		gauge("cpu_free_cores", "CPU free on the node", float64(node.Free_cpu)/1000)
+		gauge("cpu_usage_percent", "CPU usage of the node in percent", float64(node.Usage_cpu_percent))
+		gauge("cpu_request_cores", "CPU requested by the pods on the node", float64(node.Request_cpu)/1000)
+		gauge("cpu_limit_cores", "CPU limits of the pods on the node", float64(node.Limit_cpu)/1000)
+		gauge("cpu_request_percent", "CPU requested by the pods against the allocatable CPU", float64(node.Request_cpu_percent))
+		gauge("cpu_limit_percent", "CPU limits of the pods against the allocatable CPU", float64(node.Limit_cpu_percent))
+	}
+	if metric == "network" {
+		gauge("network_receive_bytes_per_second", "Bytes received per second by the node since the previous scrape", node.Rx_network)
+		gauge("network_transmit_bytes_per_second", "Bytes transmitted per second by the node since the previous scrape", node.Tx_network)
+		gauge("network_usage_percent", "Network usage of the busier direction against the --bandwidth in percent", float64(node.Usage_network_percent))
+	}
+	if metric == "disk" || metric == "all" {
+		// Capacities the node does not report are left out like the usage below
+		if node.Capacity_disk > 0 {
+			gauge("disk_capacity_bytes", "Disk capacity of the node", float64(node.Capacity_disk))
+		}
+		if node.Allocatable_disk > 0 {
+			gauge("disk_allocatable_bytes", "Disk allocatable on the node - the capacity minus the reserved resources", float64(node.Allocatable_disk))
+		}
+
+		// Leave the usage out rather than export a made up value when there is no source for it
+		if node.Disk_source == k8s.SourceUnavailable {
+			return
+		}
+		gauge("disk_usage_bytes", "Disk used on the node", float64(node.Usage_disk))
+		gauge("disk_free_bytes", "Disk free on the node", float64(node.Free_disk))
+		gauge("disk_usage_percent", "Disk usage of the node in percent", float64(node.Usage_disk_percent))
+
+		// The breakdown only comes with the kubelet summary
+		if node.Disk_source != k8s.SourceKubelet {
+			return
+		}
+		gauge("imagefs_capacity_bytes", "Capacity of the image filesystem of the node", float64(node.Capacity_imagefs))
+		gauge("imagefs_usage_bytes", "Image filesystem used on the node by images and writable layers", float64(node.Usage_imagefs))
+		gauge("imagefs_usage_percent", "Image filesystem usage of the node in percent", float64(node.Usage_imagefs_percent))
+		gauge("inodes", "Inodes of the node root filesystem", float64(node.Inodes))
+		gauge("inodes_free", "Free inodes of the node root filesystem", float64(node.Free_inodes))
+		gauge("inodes_usage_percent", "Inode usage of the node root filesystem in percent", float64(node.Usage_inodes_percent))
+		gauge("logs_usage_bytes", "Disk used by the container logs of the pods on the node", float64(node.Usage_logs))
+	}
+}
+
+func (u *UsageCollector) collectPod(ch chan<- prometheus.Metric, pod k8s.Pod) {
+	labels := []string{pod.Name, pod.Namespace, pod.NodeName}
+	if u.args.LabelToDisplay != "" {
+		labels = append(labels, pod.LabelToDisplay)
+	}
+
+	gauge := func(name string, help string, value float64) {
+		ch <- prometheus.MustNewConstMetric(u.podDesc(name, help), prometheus.GaugeValue, value, labels...)
+	}
+
+	switch u.args.Metrics {
+	case "memory":
+		gauge("memory_usage_bytes", "Memory used by the pod", float64(pod.Usage_memory)*mib)
+		gauge("memory_request_bytes", "Memory requested by the pod", float64(pod.Request_memory)*mib)
+		gauge("memory_limit_bytes", "Memory limit of the pod", float64(pod.Limit_memory)*mib)
+		gauge("memory_usage_percent", "Memory usage of the pod against its limit or the node capacity", float64(pod.Usage_memory_percent))
+	case "cpu":
+		gauge("cpu_usage_cores", "CPU used by the pod", float64(pod.Usage_cpu))
+		gauge("cpu_request_cores", "CPU requested by the pod", float64(pod.Request_cpu))
+		gauge("cpu_limit_cores", "CPU limit of the pod", float64(pod.Limit_cpu))
+		gauge("cpu_usage_percent", "CPU usage of the pod against its limit or the node capacity", float64(pod.Usage_cpu_percent))
+	case "network":
+		gauge("network_receive_bytes_per_second", "Bytes received per second by the pod since the previous scrape", pod.Rx_network)
+		gauge("network_transmit_bytes_per_second", "Bytes transmitted per second by the pod since the previous scrape", pod.Tx_network)
+		gauge("network_usage_percent", "Network usage of the busier direction against the --bandwidth in percent", float64(pod.Usage_network_percent))
+	case "disk":
+		if pod.Disk_source != k8s.SourceUnavailable {
+			gauge("disk_usage_bytes", "Disk used by the pod", pod.Usage_disk*mib)
+		}
+	}
+}
+
+func (u *UsageCollector) collectVolume(ch chan<- prometheus.Metric, volume k8s.Volume) {
+	labels := []string{volume.Name, volume.Pod, volume.Namespace, volume.NodeName, volume.Type, volume.PVC}
+	if u.args.LabelToDisplay != "" {
+		labels = append(labels, volume.LabelToDisplay)
+	}
+
+	gauge := func(name string, help string, value float64) {
+		ch <- prometheus.MustNewConstMetric(u.volumeDesc(name, help), prometheus.GaugeValue, value, labels...)
+	}
+
+	gauge("capacity_bytes", "Capacity of the volume", float64(volume.Capacity_volume))
+	gauge("usage_bytes", "Bytes used on the volume", float64(volume.Usage_volume))
+	gauge("free_bytes", "Bytes available on the volume", float64(volume.Free_volume))
+	gauge("usage_percent", "Usage of the volume in percent", float64(volume.Usage_volume_percent))
+}
@@ -0,0 +1,387 @@
+package namespacemodel
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/AKSarav/KubeNodeUsage/v3/cmd/common"
+	"github.com/AKSarav/KubeNodeUsage/v3/k8s"
+	"github.com/AKSarav/KubeNodeUsage/v3/utils"
+
+	"github.com/charmbracelet/bubbles/spinner"
+	"github.com/charmbracelet/bubbles/textinput"
+	"github.com/charmbracelet/bubbles/viewport"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+type tickMsg time.Time
+
+// statsMsg carries the pods and the cluster capacity fetched in the background
+// fetch tells which fetch it answers, the result of a superseded fetch is dropped
+type statsMsg struct {
+	fetch    int
+	stats    []k8s.Pod
+	capacity k8s.ClusterCapacity
+	err      error
+}
+
+// refreshInterval is the delay between refreshes when the API calls succeed, the one of the pods
+const refreshInterval = time.Second * 5
+
+// namespaceusage is the Bubble Tea model.
+// it keeps the pods, they are summed up per namespace on every render after the filters
+type NamespaceUsage struct {
+	ClusterInfo k8s.Cluster
+	Collector   *k8s.Collector
+	Podstats    []k8s.Pod
+	Capacity    k8s.ClusterCapacity
+	Args        *utils.Inputs
+	Format      string
+	viewport    viewport.Model
+	content     string
+	xOffset     int // Track horizontal scroll position
+	width       int // Terminal width
+	height      int // Terminal height
+	ready       bool
+	maxWidth    int // Maximum content width
+	searchInput textinput.Model
+	searching   bool
+	err         error           // last refresh error, shown as a banner while the last good data stays on screen
+	failures    int             // consecutive refresh failures, used for the retry backoff
+	rows        []k8s.Namespace // the namespaces in the order they are shown
+	cursor      int             // selected row among the visible ones
+	detail      bool            // detail pane of the selected namespace
+	paused      bool            // no refreshes while reading a frozen snapshot
+	updated     time.Time       // when the data on screen was fetched
+	fetching    bool            // a fetch is running, the refreshes asked meanwhile are dropped
+	fetches     int             // fetches started, the view waits for the last one
+	spinner     spinner.Model
+}
+
+// NewNamespaceUsage creates a new NamespaceUsage model
+func NewNamespaceUsage(args *utils.Inputs, collector *k8s.Collector) NamespaceUsage {
+	ti := textinput.New()
+	ti.Placeholder = "Search..."
+	ti.CharLimit = 156
+	ti.Width = 20
+
+	model := NamespaceUsage{
+		Args:        args,
+		searchInput: ti,
+		Collector:   collector,
+		ClusterInfo: collector.ClusterInfo(),
+		content:     "",
+		xOffset:     0,
+		width:       0,
+		height:      0,
+		ready:       false,
+		maxWidth:    0,
+		searching:   false,
+		spinner:     spinner.New(spinner.WithSpinner(spinner.Dot)),
+	}
+
+	// The initial fetch is started by Init, the model starts empty
+	model.fetches = 1
+	model.fetching = true
+
+	// Initialize content
+	model.render()
+
+	return model
+}
+
+// render redraws the content from the current stats and keeps the rows in the order they are shown
+func (m *NamespaceUsage) render() {
+	var output strings.Builder
+	m.rows = MetricsHandler(*m, &output)
+	m.content = output.String()
+
+	m.maxWidth = 0
+	for _, line := range strings.Split(m.content, "\n") {
+		if len(line) > m.maxWidth {
+			m.maxWidth = len(line)
+		}
+	}
+}
+
+// visible splits the content into the header and the row lines left after the search
+// and returns the namespaces of those lines in the same order
+func (m NamespaceUsage) visible() (header []string, lines []string, rows []k8s.Namespace) {
+	searchTerm := ""
+	if m.searching {
+		searchTerm = m.searchInput.Value()
+	}
+	return common.Visible(m.content, m.rows, searchTerm)
+}
+
+// Selected returns the namespace under the cursor
+func (m NamespaceUsage) Selected() (k8s.Namespace, bool) {
+	_, _, rows := m.visible()
+	if m.cursor < 0 || m.cursor >= len(rows) {
+		return k8s.Namespace{}, false
+	}
+	return rows[m.cursor], true
+}
+
+// moveCursor moves the selection by delta rows and scrolls the viewport to keep it in sight
+func (m *NamespaceUsage) moveCursor(delta int) {
+	header, _, rows := m.visible()
+	m.cursor = common.ClampCursor(m.cursor+delta, len(rows))
+
+	// Only a key press scrolls, a refresh leaves the viewport where it is
+	if delta != 0 {
+		m.scrollTo(len(header) + m.cursor)
+	}
+}
+
+// scrollTo scrolls the viewport just enough to show the given line above the detail pane
+func (m *NamespaceUsage) scrollTo(line int) {
+	common.ScrollTo(&m.viewport, m.bodyHeight(), line)
+}
+
+// detailPane renders the detail of the selected namespace, empty when the pane is closed
+func (m NamespaceUsage) detailPane() string {
+	namespace, ok := m.Selected()
+	if !m.detail || !ok {
+		return ""
+	}
+	return common.DetailPane(detailLines(m, namespace), m.width, m.height)
+}
+
+// bodyHeight is the height left to the viewport by the detail pane, the error banner and the help line
+func (m NamespaceUsage) bodyHeight() int {
+	return common.BodyHeight(m.height, m.err != nil, m.detailPane())
+}
+
+// refresh fetches the pods and the capacity again in the background unless a fetch is already running
+func (m *NamespaceUsage) refresh() tea.Cmd {
+	if m.fetching {
+		return nil
+	}
+	m.fetches++
+	m.fetching = true
+	return tea.Batch(m.collect(), m.spinner.Tick)
+}
+
+// collect returns the command fetching the pods and the cluster capacity off the update loop
+// with a snapshot of the inputs, so the keys can change them meanwhile
+func (m NamespaceUsage) collect() tea.Cmd {
+	fetch, inputs, collector := m.fetches, *m.Args, m.Collector
+	return func() tea.Msg {
+		stats, err := collector.PodsFor(&inputs, "")
+		if err != nil {
+			return statsMsg{fetch: fetch, err: err}
+		}
+		capacity, err := collector.ClusterCapacityFor(&inputs, stats)
+		return statsMsg{fetch: fetch, stats: stats, capacity: capacity, err: err}
+	}
+}
+
+// receive shows the fetched namespaces, or keeps the last good ones on error
+func (m *NamespaceUsage) receive(msg statsMsg) {
+	m.fetching = false
+	if msg.err != nil {
+		// Keep the last good data and retry with backoff
+		utils.Logger.Debug("Refresh failed: ", msg.err)
+		m.err = msg.err
+		m.failures++
+	} else {
+		m.Podstats = msg.stats
+		m.Capacity = msg.capacity
+		m.updated = time.Now()
+		m.err = nil
+		m.failures = 0
+	}
+	m.render()
+	m.viewport.SetContent(m.content)
+	m.moveCursor(0) // keep the cursor on a row when the list shrinks
+}
+
+// interval is the delay between refreshes, the one of --interval or changed with + and -
+func (m NamespaceUsage) interval() time.Duration {
+	if m.Args.Interval > 0 {
+		return m.Args.Interval
+	}
+	return refreshInterval
+}
+
+// Init Bubble Tea namespaceusage
+func (m NamespaceUsage) Init() tea.Cmd {
+	return tea.Batch(m.collect(), m.spinner.Tick, tickCmd(utils.Backoff(m.interval(), m.failures)), tea.EnterAltScreen)
+}
+
+func tickCmd(delay time.Duration) tea.Cmd {
+	return tea.Tick(delay, func(t time.Time) tea.Msg {
+		return tickMsg(t)
+	})
+}
+
+// Update method for Bubble Tea - for constant update loop
+func (m NamespaceUsage) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	var (
+		cmd  tea.Cmd
+		cmds []tea.Cmd
+	)
+
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		switch {
+		case msg.Type == tea.KeyCtrlC:
+			return m, tea.Quit
+		case msg.Type == tea.KeyEsc && m.searching:
+			// Exit search mode
+			m.searching = false
+			m.searchInput.Reset()
+			m.searchInput.Blur()
+		case msg.Type == tea.KeyRunes && (msg.Runes[0] == 'Q' || msg.Runes[0] == 'q') && !m.searching:
+			return m, tea.Quit
+		case msg.Type == tea.KeyRunes && (msg.Runes[0] == 'S' || msg.Runes[0] == 's') && !m.searching:
+			// Enter search mode
+			m.searching = true
+			m.searchInput.Focus()
+			return m, nil
+		case msg.Type == tea.KeyRunes && (msg.Runes[0] == 'O' || msg.Runes[0] == 'o') && !m.searching:
+			// Sort by the next column, no sort key is a sort by name
+			sortBy := m.Args.SortBy
+			if sortBy == "" {
+				sortBy = "name"
+			}
+			m.Args.SortBy = utils.Next(sortKeys, sortBy)
+			m.render()
+			return m, nil
+		case msg.Type == tea.KeyRunes && (msg.Runes[0] == 'A' || msg.Runes[0] == 'a') && !m.searching:
+			// Toggle ascending and descending
+			m.Args.ReverseFlag = !m.Args.ReverseFlag
+			m.render()
+			return m, nil
+		case msg.Type == tea.KeyRunes && (msg.Runes[0] == 'M' || msg.Runes[0] == 'm') && !m.searching:
+			// Show the next metric, the sums of both are there already
+			m.Args.Metrics = utils.Next(metrics, m.Args.Metrics)
+			m.render()
+			return m, nil
+		case msg.Type == tea.KeyRunes && (msg.Runes[0] == 'P' || msg.Runes[0] == 'p') && !m.searching:
+			// Pause or resume the refreshes
+			m.paused = !m.paused
+			m.render()
+			return m, nil
+		case msg.Type == tea.KeyRunes && (msg.Runes[0] == 'R' || msg.Runes[0] == 'r') && !m.searching:
+			// Refresh right away, paused or not
+			return m, m.refresh()
+		case msg.Type == tea.KeyRunes && (msg.Runes[0] == '+' || msg.Runes[0] == '=') && !m.searching:
+			// Refresh less often, from the next tick on
+			m.Args.Interval = m.interval() + time.Second
+			m.render()
+			return m, nil
+		case msg.Type == tea.KeyRunes && msg.Runes[0] == '-' && !m.searching:
+			// Refresh more often, down to every second
+			if interval := m.interval(); interval > time.Second {
+				m.Args.Interval = interval - time.Second
+			}
+			m.render()
+			return m, nil
+		case msg.Type == tea.KeyRunes && (msg.Runes[0] == 'I' || msg.Runes[0] == 'i') && !m.searching:
+			// Toggle the detail pane of the selected namespace
+			m.detail = !m.detail
+			if header, _, _ := m.visible(); m.detail {
+				m.scrollTo(len(header) + m.cursor)
+			}
+			return m, nil
+		}
+
+		// Row selection works while searching too
+		switch msg.String() {
+		case "up":
+			m.moveCursor(-1)
+			return m, nil
+		case "down":
+			m.moveCursor(1)
+			return m, nil
+		}
+
+		if m.searching {
+			m.searchInput, cmd = m.searchInput.Update(msg)
+			m.cursor = 0
+			cmds = append(cmds, cmd)
+			return m, tea.Batch(cmds...)
+		}
+
+		// Handle horizontal scrolling only when not searching
+		switch msg.String() {
+		case "left":
+			if m.xOffset > 0 {
+				m.xOffset -= 5
+			}
+		case "right":
+			maxScroll := m.maxWidth - m.width
+			if maxScroll > 0 && m.xOffset < maxScroll {
+				m.xOffset = common.Min(m.xOffset+5, maxScroll)
+			}
+		}
+	case tea.WindowSizeMsg:
+		if !m.ready {
+			m.viewport = viewport.New(msg.Width, msg.Height-1)
+			m.ready = true
+		}
+		m.width = msg.Width
+		m.height = msg.Height
+		m.viewport.Width = msg.Width
+		m.viewport.Height = msg.Height - 1
+	case tickMsg:
+		if !m.paused {
+			cmds = append(cmds, m.refresh())
+		}
+		cmds = append(cmds, tickCmd(utils.Backoff(m.interval(), m.failures)))
+	case statsMsg:
+		if msg.fetch == m.fetches {
+			m.receive(msg)
+		}
+	case spinner.TickMsg:
+		// The spinner stops with the fetch
+		if m.fetching {
+			m.spinner, cmd = m.spinner.Update(msg)
+			cmds = append(cmds, cmd)
+		}
+	}
+
+	m.viewport, cmd = m.viewport.Update(msg)
+	cmds = append(cmds, cmd)
+	return m, tea.Batch(cmds...)
+}
+
+// View renders bubble tea
+func (m NamespaceUsage) View() string {
+	if !m.ready {
+		return "Initializing..."
+	}
+
+	header, lines, _ := m.visible()
+	m.viewport.SetContent(common.Table(header, lines, m.xOffset, m.cursor))
+
+	var helpText string
+	if m.searching {
+		matchCount := len(lines)
+		helpText = fmt.Sprintf("\n%s %s (%d matches) (ESC to exit search)",
+			common.SearchStyle.Render("Search:"),
+			m.searchInput.View(),
+			matchCount)
+	} else {
+		helpText = common.HelpStyle("\nUse ↑ and ↓ to select, I for its details, ← and → to scroll horizontally, S to search, O, A and M to change the sort, order and metric, P to pause, R to refresh, + and - for the interval, Q or Ctrl+C to quit")
+	}
+	if m.fetching {
+		helpText = "\n" + m.spinner.View() + " Refreshing " + strings.TrimPrefix(helpText, "\n")
+	}
+
+	// The viewport gives up lines for the detail pane and the error banner
+	m.viewport.Height = m.bodyHeight()
+
+	var pane string
+	if detail := m.detailPane(); detail != "" {
+		pane = "\n" + detail
+	}
+
+	// Error banner for a failed refresh
+	banner := common.Banner(m.err, m.interval(), m.failures, m.width)
+
+	return fmt.Sprintf("%s%s%s%s", m.viewport.View(), pane, banner, helpText)
+}
@@ -0,0 +1,210 @@
+package namespacemodel
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/AKSarav/KubeNodeUsage/v3/cmd/common"
+	"github.com/AKSarav/KubeNodeUsage/v3/k8s"
+	"github.com/AKSarav/KubeNodeUsage/v3/utils"
+
+	"github.com/iancoleman/strcase"
+)
+
+// metrics are the metrics the M key cycles through, the ones summed up per namespace
+var metrics = []string{"memory", "cpu"}
+
+// sortKeys are the sort keys the O key cycles through
+var sortKeys = []string{"name", "pods", "usage", "request", "limit"}
+
+// topPods is how many pods the detail pane lists for the selected namespace
+const topPods = 5
+
+func getUnit(metricType string) string {
+	if metricType == "cpu" {
+		return "Cores"
+	}
+	return "MB"
+}
+
+func DebugView(m NamespaceUsage, output *strings.Builder) {
+	if m.Args.Debug {
+		fmt.Fprint(output, " \nDebug mode enabled")
+		fmt.Fprint(output, "\nArgs: ", m.Args)
+		fmt.Fprint(output, "\nCapacity: ", m.Capacity)
+	}
+}
+
+func RightMetric(namespace k8s.Namespace, sortBy string, metric string) float32 {
+	switch sortBy {
+	case "pods":
+		return float32(namespace.Pods)
+	case "request":
+		if metric == "cpu" {
+			return namespace.Request_cpu
+		}
+		return float32(namespace.Request_memory)
+	case "limit":
+		if metric == "cpu" {
+			return namespace.Limit_cpu
+		}
+		return float32(namespace.Limit_memory)
+	}
+	// default return - usage and color
+	return namespace.UsagePercent(metric)
+}
+
+func SortByHandler(namespaces []k8s.Namespace, args *utils.Inputs) {
+	switch args.SortBy {
+	case "", "name", "namespace":
+		sort.Slice(namespaces, func(i, j int) bool {
+			return (namespaces[i].Name < namespaces[j].Name) != args.ReverseFlag
+		})
+	default:
+		sort.SliceStable(namespaces, func(i, j int) bool {
+			if args.ReverseFlag {
+				return RightMetric(namespaces[i], args.SortBy, args.Metrics) > RightMetric(namespaces[j], args.SortBy, args.Metrics)
+			}
+			return RightMetric(namespaces[i], args.SortBy, args.Metrics) < RightMetric(namespaces[j], args.SortBy, args.Metrics)
+		})
+	}
+}
+
+// Rows sums up the pods per namespace and returns the namespaces after the filters and the sort from the inputs
+// --filternodes and --filterlabel pick the pods summed up, --filtercolor is for the share of the cluster
+func Rows(m NamespaceUsage) []k8s.Namespace {
+	podInputs := *m.Args
+	podInputs.FilterColor = ""
+	pods := k8s.Filter(m.Podstats, &podInputs)
+
+	namespaces := ApplyFilters(k8s.ByNamespace(pods, m.Capacity), m.Args)
+	SortByHandler(namespaces, m.Args)
+	return namespaces
+}
+
+// ApplyFilters keeps the namespaces in the color range of the inputs
+// the other filters were applied to the pods already
+func ApplyFilters(namespaces []k8s.Namespace, args *utils.Inputs) []k8s.Namespace {
+	colorInputs := *args
+	colorInputs.FilterNodes = ""
+	colorInputs.FilterLabel = ""
+	return k8s.Filter(namespaces, &colorInputs)
+}
+
+func PrintDesign(output *strings.Builder, maxNsWidth int) {
+	output.WriteString(strings.Repeat("-", maxNsWidth+72) + "\n")
+}
+
+func headlinePrinter(m *NamespaceUsage, output *strings.Builder, maxNsWidth int) {
+	unit := getUnit(m.Args.Metrics)
+	m.Format = "%-" + strconv.Itoa(maxNsWidth) + "s %-6s %-12s %-14s %-12s %-16s %s\n"
+	fmt.Fprintf(output, m.Format, "Namespace", "Pods", "Usage("+unit+")", "Request("+unit+")", "Limit("+unit+")", "Cluster("+unit+")", "Usage%")
+}
+
+// podUsage sums up the usage of the containers of the pod, they have both metrics
+// whatever the metric the pods were collected for
+func podUsage(pod k8s.Pod, metric string) float32 {
+	var usage float32
+	for _, container := range pod.Containers {
+		if metric == "cpu" {
+			usage += container.Usage_cpu
+		} else {
+			usage += float32(container.Usage_memory)
+		}
+	}
+	return usage
+}
+
+// amount formats a memory or cpu amount the way the pod view does
+func amount(metric string, memory int, cpu float32) string {
+	if metric == "cpu" {
+		return fmt.Sprintf("%.2f", cpu)
+	}
+	return strconv.Itoa(memory)
+}
+
+func MetricsHandler(m NamespaceUsage, output *strings.Builder) []k8s.Namespace {
+	// Summing up, filtering and sorting based on the inputs
+	namespaces := Rows(m)
+
+	// decide formatting and Maximum width
+	maxNsWidth := 12
+	for _, namespace := range namespaces {
+		if maxNsWidth < len(namespace.Name) {
+			maxNsWidth = len(namespace.Name)
+		}
+	}
+	maxNsWidth += 2
+
+	// Header and Version info
+	fmt.Fprintf(output, "\n# KubeNodeUsage - Namespace View\n# Version: %s\n# https://github.com/AKSarav/KubeNodeUsage\n\n", utils.Version)
+
+	if !m.Args.NoInfo {
+		fmt.Fprint(output, "\n# Context: ", m.ClusterInfo.Context, "\n# Version: ", m.ClusterInfo.Version, "\n# URL: ", m.ClusterInfo.URL, "\n\n")
+	}
+
+	fmt.Fprint(output, "# ", strcase.ToCamel(m.Args.Metrics), " Metrics by Namespace - Usage% is the share of the cluster\n")
+	fmt.Fprint(output, "# Sorted by: ", utils.SortIndicator(m.Args.SortBy, m.Args.ReverseFlag),
+		"\n# Last updated: ", utils.RefreshIndicator(m.updated, m.interval(), m.paused), "\n\n")
+
+	headlinePrinter(&m, output, maxNsWidth)
+	PrintDesign(output, maxNsWidth)
+
+	for _, namespace := range namespaces {
+		usagePercent := namespace.UsagePercent(m.Args.Metrics)
+		prog := common.GetBar(float64(usagePercent) / 100.0)
+		values := []interface{}{
+			namespace.Name,
+			strconv.Itoa(namespace.Pods),
+			amount(m.Args.Metrics, namespace.Usage_memory, namespace.Usage_cpu),
+			amount(m.Args.Metrics, namespace.Request_memory, namespace.Request_cpu),
+			amount(m.Args.Metrics, namespace.Limit_memory, namespace.Limit_cpu),
+			amount(m.Args.Metrics, namespace.Capacity_memory, namespace.Capacity_cpu),
+			prog.ViewAs(float64(usagePercent) / 100.0),
+		}
+		fmt.Fprintf(output, m.Format, values...)
+	}
+	return namespaces
+}
+
+// detailLines describes the selected namespace for the detail pane
+// both metrics against the cluster and the pods using the most of the metric on screen
+func detailLines(m NamespaceUsage, namespace k8s.Namespace) []string {
+	var lines []string
+	lines = append(lines, fmt.Sprintf("Namespace: %s   Pods: %d", namespace.Name, namespace.Pods))
+
+	format := "%-12s %-30s %s"
+	lines = append(lines, fmt.Sprintf(format, "", "use/req/lim", "Cluster"))
+	lines = append(lines, fmt.Sprintf(format, "CPU(Cores)",
+		fmt.Sprintf("%.2f/%.2f/%.2f", namespace.Usage_cpu, namespace.Request_cpu, namespace.Limit_cpu),
+		fmt.Sprintf("%.2f (%.1f%% used)", namespace.Capacity_cpu, namespace.Usage_cpu_percent)))
+	lines = append(lines, fmt.Sprintf(format, "Memory(MB)",
+		fmt.Sprintf("%d/%d/%d", namespace.Usage_memory, namespace.Request_memory, namespace.Limit_memory),
+		fmt.Sprintf("%d (%.1f%% used)", namespace.Capacity_memory, namespace.Usage_memory_percent)))
+
+	// The pods summed up in the namespace, the biggest first
+	podInputs := *m.Args
+	podInputs.FilterColor = ""
+	var pods []k8s.Pod
+	for _, pod := range k8s.Filter(m.Podstats, &podInputs) {
+		if pod.Namespace == namespace.Name {
+			pods = append(pods, pod)
+		}
+	}
+	sort.SliceStable(pods, func(i, j int) bool {
+		return podUsage(pods[i], m.Args.Metrics) > podUsage(pods[j], m.Args.Metrics)
+	})
+	if len(pods) > topPods {
+		pods = pods[:topPods]
+	}
+
+	var top []string
+	for _, pod := range pods {
+		usage := podUsage(pod, m.Args.Metrics)
+		top = append(top, pod.Name+" "+amount(m.Args.Metrics, int(usage), usage))
+	}
+	lines = append(lines, fmt.Sprintf("%-12s %s", "Top pods("+getUnit(m.Args.Metrics)+")", strings.Join(top, "  ")))
+	return lines
+}
@@ -5,10 +5,11 @@ import (
 	"strings"
 	"time"
 
+	"github.com/AKSarav/KubeNodeUsage/v3/cmd/common"
 	"github.com/AKSarav/KubeNodeUsage/v3/k8s"
 	"github.com/AKSarav/KubeNodeUsage/v3/utils"
 
-	"github.com/charmbracelet/bubbles/progress"
+	"github.com/charmbracelet/bubbles/spinner"
 	"github.com/charmbracelet/bubbles/textinput"
 	"github.com/charmbracelet/bubbles/viewport"
 	tea "github.com/charmbracelet/bubbletea"
@@ -16,16 +17,27 @@ import (
 )
 
 var (
-	helpStyle   = lipgloss.NewStyle().Foreground(lipgloss.Color("#626262")).Render
-	searchStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("#FFFF00")).Bold(true)
+	staleStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("#FFA500"))
 	// highlightStyle = lipgloss.NewStyle().Background(lipgloss.Color("#fff0f4"))
 )
 
 type tickMsg time.Time
 
+// statsMsg carries the nodes fetched in the background
+// fetch tells which fetch it answers, the result of a superseded fetch is dropped
+type statsMsg struct {
+	fetch int
+	stats []k8s.Node
+	err   error
+}
+
+// refreshInterval is the delay between refreshes when the API calls succeed
+const refreshInterval = time.Second * 1
+
 // nodeusage is the Bubble Tea model.
 type NodeUsage struct {
 	ClusterInfo k8s.Cluster
+	Collector   *k8s.Collector
 	Nodestats   []k8s.Node
 	Args        *utils.Inputs
 	Format      string
@@ -38,10 +50,20 @@ type NodeUsage struct {
 	maxWidth    int // Maximum content width
 	searchInput textinput.Model
 	searching   bool
+	err         error      // last refresh error, shown as a banner while the last good data stays on screen
+	failures    int        // consecutive refresh failures, used for the retry backoff
+	rows        []k8s.Node // the nodes in the order they are shown
+	cursor      int        // selected row among the visible ones
+	detail      bool       // detail pane of the selected node
+	paused      bool       // no refreshes while reading a frozen snapshot
+	updated     time.Time  // when the data on screen was fetched
+	fetching    bool       // a fetch is running, the refreshes asked meanwhile are dropped
+	fetches     int        // fetches started, the view waits for the last one
+	spinner     spinner.Model
 }
 
 // NewNodeUsage creates a new NodeUsage model
-func NewNodeUsage(args *utils.Inputs) NodeUsage {
+func NewNodeUsage(args *utils.Inputs, collector *k8s.Collector) NodeUsage {
 	ti := textinput.New()
 	ti.Placeholder = "Search..."
 	ti.CharLimit = 156
@@ -50,8 +72,8 @@ func NewNodeUsage(args *utils.Inputs) NodeUsage {
 	model := NodeUsage{
 		Args:        args,
 		searchInput: ti,
-		ClusterInfo: k8s.ClusterInfo(),
-		Nodestats:   k8s.Nodes(args),
+		Collector:   collector,
+		ClusterInfo: collector.ClusterInfo(),
 		Format:      "table",
 		content:     "",
 		xOffset:     0,
@@ -60,26 +82,160 @@ func NewNodeUsage(args *utils.Inputs) NodeUsage {
 		ready:       false,
 		maxWidth:    0,
 		searching:   false,
+		spinner:     spinner.New(spinner.WithSpinner(spinner.Dot)),
 	}
 
+	// The initial fetch is started by Init, the model starts empty
+	model.fetches = 1
+	model.fetching = true
+
 	// Initialize content
+	model.render()
+
+	return model
+}
+
+// render redraws the content from the current stats and keeps the rows in the order they are shown
+func (m *NodeUsage) render() {
 	var output strings.Builder
-	MetricsHandler(model, &output)
-	model.content = output.String()
+	m.rows = MetricsHandler(*m, &output)
+	m.content = output.String()
 
-	// Calculate initial maxWidth
-	for _, line := range strings.Split(model.content, "\n") {
-		if len(line) > model.maxWidth {
-			model.maxWidth = len(line)
+	m.maxWidth = 0
+	for _, line := range strings.Split(m.content, "\n") {
+		if len(line) > m.maxWidth {
+			m.maxWidth = len(line)
 		}
 	}
+}
 
-	return model
+// visible splits the content into the header and the row lines left after the search
+// and returns the nodes of those lines in the same order
+func (m NodeUsage) visible() (header []string, lines []string, rows []k8s.Node) {
+	searchTerm := ""
+	if m.searching {
+		searchTerm = m.searchInput.Value()
+	}
+	return common.Visible(m.content, m.rows, searchTerm)
+}
+
+// Selected returns the node under the cursor
+func (m NodeUsage) Selected() (k8s.Node, bool) {
+	_, _, rows := m.visible()
+	if m.cursor < 0 || m.cursor >= len(rows) {
+		return k8s.Node{}, false
+	}
+	return rows[m.cursor], true
+}
+
+// Searching tells if the search input has the keys
+func (m NodeUsage) Searching() bool {
+	return m.searching
+}
+
+// moveCursor moves the selection by delta rows and scrolls the viewport to keep it in sight
+func (m *NodeUsage) moveCursor(delta int) {
+	header, _, rows := m.visible()
+	m.cursor = common.ClampCursor(m.cursor+delta, len(rows))
+
+	// Only a key press scrolls, a refresh leaves the viewport where it is
+	if delta != 0 {
+		m.scrollTo(len(header) + m.cursor)
+	}
+}
+
+// scrollTo scrolls the viewport just enough to show the given line above the detail pane
+func (m *NodeUsage) scrollTo(line int) {
+	common.ScrollTo(&m.viewport, m.bodyHeight(), line)
+}
+
+// detailPane renders the detail of the selected node, empty when the pane is closed
+// it takes at most half of the screen, long label lists wrap within it
+func (m NodeUsage) detailPane() string {
+	node, ok := m.Selected()
+	if !m.detail || !ok {
+		return ""
+	}
+	return common.DetailPane(detailLines(m, node), m.width, m.height)
+}
+
+// bodyHeight is the height left to the viewport by the detail pane, the error banner and the help line
+func (m NodeUsage) bodyHeight() int {
+	return common.BodyHeight(m.height, m.err != nil, m.detailPane())
+}
+
+// refresh fetches the nodes again in the background unless a fetch is already running
+func (m *NodeUsage) refresh() tea.Cmd {
+	if m.fetching {
+		return nil
+	}
+	return m.fetch()
+}
+
+// fetch starts a fetch of the nodes, superseding the one running if any
+func (m *NodeUsage) fetch() tea.Cmd {
+	m.fetches++
+	m.fetching = true
+	return tea.Batch(m.collect(), m.spinner.Tick)
+}
+
+// collect returns the command fetching the nodes off the update loop
+// with a snapshot of the inputs, so the keys can change them meanwhile
+func (m NodeUsage) collect() tea.Cmd {
+	fetch, inputs, collector := m.fetches, *m.Args, m.Collector
+	return func() tea.Msg {
+		stats, err := collector.NodesFor(&inputs)
+		return statsMsg{fetch: fetch, stats: stats, err: err}
+	}
+}
+
+// receive shows the fetched nodes, or keeps the last good ones on error
+func (m *NodeUsage) receive(msg statsMsg) {
+	m.fetching = false
+	if msg.err != nil {
+		// Keep the last good data and retry with backoff
+		utils.Logger.Debug("Refresh failed: ", msg.err)
+		m.err = msg.err
+		m.failures++
+	} else {
+		m.Nodestats = msg.stats
+		m.updated = time.Now()
+		m.err = nil
+		m.failures = 0
+	}
+	m.render()
+	m.viewport.SetContent(m.content)
+	m.moveCursor(0) // keep the cursor on a row when the list shrinks
+}
+
+// interval is the delay between refreshes, the one of --interval or changed with + and -
+func (m NodeUsage) interval() time.Duration {
+	if m.Args.Interval > 0 {
+		return m.Args.Interval
+	}
+	return refreshInterval
+}
+
+// switchMetric moves to the next metric and fetches it, the fetch of the previous metric is dropped
+// the sort is kept when the new metric has it, else the nodes are sorted by name
+func (m *NodeUsage) switchMetric() tea.Cmd {
+	m.Args.Metrics = utils.Next(metrics, m.Args.Metrics)
+	if m.Args.Metrics != "disk" {
+		m.Args.DiskDetail = false
+	}
+	if keys := sortKeys(m.Args.Metrics); !utils.Contains(keys, m.Args.SortBy) {
+		_, key := utils.SortMetric(m.Args.SortBy, m.Args.Metrics)
+		if !utils.Contains(keys, key) {
+			key = "name"
+		}
+		m.Args.SortBy = key
+	}
+	return m.fetch()
 }
 
 // Init Bubble Tea nodeusage
 func (m NodeUsage) Init() tea.Cmd {
-	return tea.Batch(tickCmd(), tea.EnterAltScreen)
+	return tea.Batch(m.collect(), m.spinner.Tick, tickCmd(utils.Backoff(m.interval(), m.failures)), tea.EnterAltScreen)
 }
 
 // Update method for Bubble Tea - for constant update loop
@@ -106,10 +262,70 @@ func (m NodeUsage) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			m.searching = true
 			m.searchInput.Focus()
 			return m, nil
+		case msg.Type == tea.KeyRunes && (msg.Runes[0] == 'D' || msg.Runes[0] == 'd') && !m.searching && m.Args.Metrics == "disk":
+			// Toggle the disk detail columns
+			m.Args.DiskDetail = !m.Args.DiskDetail
+			m.render()
+			return m, nil
+		case msg.Type == tea.KeyRunes && (msg.Runes[0] == 'O' || msg.Runes[0] == 'o') && !m.searching:
+			// Sort by the next column, no sort key is a sort by name
+			sortBy := m.Args.SortBy
+			if sortBy == "" {
+				sortBy = "name"
+			}
+			m.Args.SortBy = utils.Next(sortKeys(m.Args.Metrics), sortBy)
+			m.render()
+			return m, nil
+		case msg.Type == tea.KeyRunes && (msg.Runes[0] == 'A' || msg.Runes[0] == 'a') && !m.searching:
+			// Toggle ascending and descending
+			m.Args.ReverseFlag = !m.Args.ReverseFlag
+			m.render()
+			return m, nil
+		case msg.Type == tea.KeyRunes && (msg.Runes[0] == 'M' || msg.Runes[0] == 'm') && !m.searching:
+			// Show the next metric
+			return m, m.switchMetric()
+		case msg.Type == tea.KeyRunes && (msg.Runes[0] == 'P' || msg.Runes[0] == 'p') && !m.searching:
+			// Pause or resume the refreshes
+			m.paused = !m.paused
+			m.render()
+			return m, nil
+		case msg.Type == tea.KeyRunes && (msg.Runes[0] == 'R' || msg.Runes[0] == 'r') && !m.searching:
+			// Refresh right away, paused or not
+			return m, m.refresh()
+		case msg.Type == tea.KeyRunes && (msg.Runes[0] == '+' || msg.Runes[0] == '=') && !m.searching:
+			// Refresh less often, from the next tick on
+			m.Args.Interval = m.interval() + time.Second
+			m.render()
+			return m, nil
+		case msg.Type == tea.KeyRunes && msg.Runes[0] == '-' && !m.searching:
+			// Refresh more often, down to every second
+			if interval := m.interval(); interval > time.Second {
+				m.Args.Interval = interval - time.Second
+			}
+			m.render()
+			return m, nil
+		case msg.Type == tea.KeyRunes && (msg.Runes[0] == 'I' || msg.Runes[0] == 'i') && !m.searching:
+			// Toggle the detail pane of the selected node
+			m.detail = !m.detail
+			if header, _, _ := m.visible(); m.detail {
+				m.scrollTo(len(header) + m.cursor)
+			}
+			return m, nil
+		}
+
+		// Row selection works while searching too
+		switch msg.String() {
+		case "up":
+			m.moveCursor(-1)
+			return m, nil
+		case "down":
+			m.moveCursor(1)
+			return m, nil
 		}
 
 		if m.searching {
 			m.searchInput, cmd = m.searchInput.Update(msg)
+			m.cursor = 0
 			cmds = append(cmds, cmd)
 			return m, tea.Batch(cmds...)
 		}
@@ -123,7 +339,7 @@ func (m NodeUsage) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		case "right":
 			maxScroll := m.maxWidth - m.width
 			if maxScroll > 0 && m.xOffset < maxScroll {
-				m.xOffset = min(m.xOffset+5, maxScroll)
+				m.xOffset = common.Min(m.xOffset+5, maxScroll)
 			}
 		}
 	case tea.WindowSizeMsg:
@@ -137,33 +353,23 @@ func (m NodeUsage) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.viewport.Height = msg.Height - 1
 
 		// Re-render content with new size
-		var output strings.Builder
-		MetricsHandler(m, &output)
-		m.content = output.String()
-
-		// Recalculate maxWidth
-		m.maxWidth = 0
-		for _, line := range strings.Split(m.content, "\n") {
-			if len(line) > m.maxWidth {
-				m.maxWidth = len(line)
-			}
-		}
+		m.render()
+		m.viewport.SetContent(m.content)
 	case tickMsg:
-		m.ClusterInfo = k8s.ClusterInfo()
-		m.Nodestats = k8s.Nodes(m.Args)
-		var output strings.Builder
-		MetricsHandler(m, &output)
-		m.content = output.String()
-
-		m.maxWidth = 0
-		for _, line := range strings.Split(m.content, "\n") {
-			if len(line) > m.maxWidth {
-				m.maxWidth = len(line)
-			}
+		if !m.paused {
+			cmds = append(cmds, m.refresh())
+		}
+		cmds = append(cmds, tickCmd(utils.Backoff(m.interval(), m.failures)))
+	case statsMsg:
+		if msg.fetch == m.fetches {
+			m.receive(msg)
+		}
+	case spinner.TickMsg:
+		// The spinner stops with the fetch
+		if m.fetching {
+			m.spinner, cmd = m.spinner.Update(msg)
+			cmds = append(cmds, cmd)
 		}
-
-		m.viewport.SetContent(m.content)
-		cmds = append(cmds, tickCmd())
 	}
 
 	m.viewport, cmd = m.viewport.Update(msg)
@@ -171,94 +377,50 @@ func (m NodeUsage) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	return m, tea.Batch(cmds...)
 }
 
-func GetBar(decider float64) progress.Model {
-
-	decider = decider * 100
-
-	var prog progress.Model
-	// decide which color to use based on the usage percentage below 30% is green, above 70% is red, else yellow
-	if decider < 30 {
-		prog = progress.New(progress.WithScaledGradient("#0bad5d", "#74b03f"))
-	} else if decider > 70 {
-		prog = progress.New(progress.WithScaledGradient("#13B013", "#F11658"))
-	} else {
-		prog = progress.New(progress.WithScaledGradient("#13B013", "#F18016"))
-	}
-	return prog
-}
-
 // View renders bubble tea
 func (m NodeUsage) View() string {
 	if !m.ready {
 		return "Initializing..."
 	}
 
-	lines := strings.Split(m.content, "\n")
-	var displayLines []string
-
-	searchTerm := strings.ToLower(m.searchInput.Value())
-
-	// Always include the header lines (first 6 lines)
-	for i := 0; i < min(utils.HeaderLines, len(lines)); i++ {
-		if len(lines[i]) > m.xOffset {
-			displayLines = append(displayLines, lines[i][m.xOffset:])
-		} else {
-			displayLines = append(displayLines, "")
-		}
-	}
-
-	// For the rest of the content
-	for i := utils.HeaderLines; i < len(lines); i++ {
-		line := lines[i]
-		// If searching, only include lines that match the search term
-		if m.searching && searchTerm != "" {
-			if strings.Contains(strings.ToLower(line), searchTerm) {
-				if len(line) > m.xOffset {
-					displayLine := line[m.xOffset:]
-					// displayLine = highlightStyle.Render(displayLine)
-					displayLines = append(displayLines, displayLine)
-				} else {
-					displayLines = append(displayLines, "")
-				}
-			}
-		} else {
-			// If not searching, include all lines
-			if len(line) > m.xOffset {
-				displayLines = append(displayLines, line[m.xOffset:])
-			} else {
-				displayLines = append(displayLines, "")
-			}
-		}
-	}
-
-	viewportContent := strings.Join(displayLines, "\n")
-	m.viewport.SetContent(viewportContent)
+	header, lines, _ := m.visible()
+	m.viewport.SetContent(common.Table(header, lines, m.xOffset, m.cursor))
 
 	var helpText string
 	if m.searching {
-		matchCount := len(displayLines) - utils.HeaderLines // Subtract header lines
+		matchCount := len(lines)
 		helpText = fmt.Sprintf("\n%s %s (%d matches) (ESC to exit search)",
-			searchStyle.Render("Search:"),
+			common.SearchStyle.Render("Search:"),
 			m.searchInput.View(),
 			matchCount)
 	} else {
-		helpText = helpStyle("\nUse ← and → to scroll horizontally, S to search, Q or Ctrl+C to quit")
+		help := "\nUse ↑ and ↓ to select, Enter for its pods, I for its details, ← and → to scroll horizontally, S to search, O, A and M to change the sort, order and metric, P to pause, R to refresh, + and - for the interval, Q or Ctrl+C to quit"
+		if m.Args.Metrics == "disk" {
+			help = "\nUse ↑ and ↓ to select, Enter for its pods, I for its details, ← and → to scroll horizontally, S to search, O, A and M to change the sort, order and metric, P to pause, R to refresh, + and - for the interval, D for disk detail, Q or Ctrl+C to quit"
+		}
+		helpText = common.HelpStyle(help)
+	}
+	if m.fetching {
+		helpText = "\n" + m.spinner.View() + " Refreshing " + strings.TrimPrefix(helpText, "\n")
+	}
+
+	// The viewport gives up lines for the detail pane and the error banner
+	m.viewport.Height = m.bodyHeight()
+
+	var pane string
+	if detail := m.detailPane(); detail != "" {
+		pane = "\n" + detail
 	}
 
-	return fmt.Sprintf("%s%s", m.viewport.View(), helpText)
+	// Error banner for a failed refresh
+	banner := common.Banner(m.err, m.interval(), m.failures, m.width)
+
+	return fmt.Sprintf("%s%s%s%s", m.viewport.View(), pane, banner, helpText)
 }
 
-// tickCmd returns a command that sends a tick every second.
-func tickCmd() tea.Cmd {
-	return tea.Tick(time.Second*1, func(t time.Time) tea.Msg {
+// tickCmd returns a command that sends a tick after the given delay.
+func tickCmd(delay time.Duration) tea.Cmd {
+	return tea.Tick(delay, func(t time.Time) tea.Msg {
 		return tickMsg(t)
 	})
 }
-
-// Helper function to get minimum of two integers
-func min(a, b int) int {
-	if a < b {
-		return a
-	}
-	return b
-}
@@ -2,12 +2,11 @@ package nodemodel
 
 import (
 	"fmt"
-	"os"
-	"regexp"
 	"sort"
 	"strconv"
 	"strings"
 
+	"github.com/AKSarav/KubeNodeUsage/v3/cmd/common"
 	"github.com/AKSarav/KubeNodeUsage/v3/k8s"
 	"github.com/AKSarav/KubeNodeUsage/v3/utils"
 
@@ -23,6 +22,8 @@ func getUnit(metricType string) string {
 		unit = "Cores"
 	case "disk":
 		unit = "GB"
+	case "network":
+		unit = "KB/s"
 	}
 	return unit
 }
@@ -37,31 +38,63 @@ func DebugView(m NodeUsage, output *strings.Builder) {
 
 func RightMetric(m NodeUsage, index int) float32 {
 
-	switch m.Args.Metrics {
+	// Sort keys can address a metric directly e.g. cpu.usage for the combined view
+	metric, sortBy := utils.SortMetric(m.Args.SortBy, m.Args.Metrics)
+
+	switch metric {
 	case "memory":
-		if m.Args.SortBy == "free" {
+		if sortBy == "free" {
 			return float32(m.Nodestats[index].Free_memory)
-		} else if m.Args.SortBy == "capacity" || m.Args.SortBy == "max" {
-			return float32(m.Nodestats[index].Capacity_memory)
-		} else if m.Args.SortBy == "color" || m.Args.SortBy == "usage" {
+		} else if sortBy == "capacity" || sortBy == "max" {
+			maxMemory, _, _ := m.Nodestats[index].Max(m.Args.Basis)
+			return float32(maxMemory)
+		} else if sortBy == "allocatable" {
+			return float32(m.Nodestats[index].Allocatable_memory)
+		} else if sortBy == "request" {
+			return m.Nodestats[index].Request_memory_percent
+		} else if sortBy == "limit" {
+			return m.Nodestats[index].Limit_memory_percent
+		} else if sortBy == "color" || sortBy == "usage" {
 			return m.Nodestats[index].Usage_memory_percent
 		}
 	case "cpu":
-		if m.Args.SortBy == "free" {
+		if sortBy == "free" {
 			return float32(m.Nodestats[index].Free_cpu)
-		} else if m.Args.SortBy == "capacity" || m.Args.SortBy == "max" {
-			return float32(m.Nodestats[index].Capacity_cpu)
-		} else if m.Args.SortBy == "color" || m.Args.SortBy == "usage" {
+		} else if sortBy == "capacity" || sortBy == "max" {
+			_, maxCpu, _ := m.Nodestats[index].Max(m.Args.Basis)
+			return float32(maxCpu)
+		} else if sortBy == "allocatable" {
+			return float32(m.Nodestats[index].Allocatable_cpu)
+		} else if sortBy == "request" {
+			return m.Nodestats[index].Request_cpu_percent
+		} else if sortBy == "limit" {
+			return m.Nodestats[index].Limit_cpu_percent
+		} else if sortBy == "color" || sortBy == "usage" {
 			return m.Nodestats[index].Usage_cpu_percent
 		}
 	case "disk":
-		if m.Args.SortBy == "free" {
+		if sortBy == "free" {
 			return float32(m.Nodestats[index].Free_disk)
-		} else if m.Args.SortBy == "capacity" || m.Args.SortBy == "max" {
-			return float32(m.Nodestats[index].Capacity_disk)
-		} else if m.Args.SortBy == "color" || m.Args.SortBy == "usage" {
+		} else if sortBy == "capacity" || sortBy == "max" {
+			_, _, maxDisk := m.Nodestats[index].Max(m.Args.Basis)
+			return float32(maxDisk)
+		} else if sortBy == "allocatable" {
+			return float32(m.Nodestats[index].Allocatable_disk)
+		} else if sortBy == "color" || sortBy == "usage" {
 			return m.Nodestats[index].Usage_disk_percent
 		}
+	case "network":
+		if sortBy == "free" {
+			return float32(m.Nodestats[index].Free_network)
+		} else if sortBy == "capacity" || sortBy == "max" {
+			return float32(m.Nodestats[index].Capacity_network)
+		} else if sortBy == "rx" {
+			return float32(m.Nodestats[index].Rx_network)
+		} else if sortBy == "tx" {
+			return float32(m.Nodestats[index].Tx_network)
+		} else if sortBy == "color" || sortBy == "usage" {
+			return m.Nodestats[index].Usage_network_percent
+		}
 	}
 	// default return
 	return m.Nodestats[index].Usage_memory_percent
@@ -70,16 +103,18 @@ func RightMetric(m NodeUsage, index int) float32 {
 func SortByHandler(m NodeUsage) {
 
 	if m.Args.SortBy != "" && m.Args.SortBy != "name" && m.Args.SortBy != "node" {
-		if !m.Args.ReverseFlag {
-			sort.Slice(m.Nodestats, func(i, j int) bool {
-				return RightMetric(m, i) < RightMetric(m, j)
-			})
-		} else {
-			sort.Slice(m.Nodestats, func(i, j int) bool {
+		// The nodes without a usage for the metric go last whatever the order
+		metric, _ := utils.SortMetric(m.Args.SortBy, m.Args.Metrics)
+		sort.Slice(m.Nodestats, func(i, j int) bool {
+			if available := m.Nodestats[i].Available(metric); available != m.Nodestats[j].Available(metric) {
+				return available
+			}
+			if m.Args.ReverseFlag {
 				return RightMetric(m, i) > RightMetric(m, j)
-			})
-		}
-	} else if m.Args.SortBy != "name" || m.Args.SortBy != "node" {
+			}
+			return RightMetric(m, i) < RightMetric(m, j)
+		})
+	} else {
 		if !m.Args.ReverseFlag {
 			sort.Slice(m.Nodestats, func(i, j int) bool {
 				return m.Nodestats[i].Name < m.Nodestats[j].Name
@@ -92,131 +127,112 @@ func SortByHandler(m NodeUsage) {
 	}
 
 }
-func ApplyFilters(m NodeUsage) []k8s.Node {
-	if m.Args.FilterLabel != "" {
-		return FilterForLabel(m)
-	} else if m.Args.FilterNodes != "" {
-		return FilterForNode(m)
-	} else if m.Args.FilterColor != "" {
-		return FilterForColor(m)
-	} else {
-		return FilterForColor(m)
-	}
-}
-
-func FilterForNode(m NodeUsage) []k8s.Node {
-	var filteredNodes []k8s.Node
-	FilterNodeInput := strings.Split(m.Args.FilterNodes, ",")
 
-	// Creating a new map to store the values of NodeStats list
-	// Choosing Map over Nested Array for comparision is best for TimeComplexity
-	//NodesMap := make(map[string]k8s.Node)
+// metrics are the metrics the M key cycles through
+var metrics = []string{"memory", "cpu", "disk", "network", "all"}
 
-	for _, node := range m.Nodestats {
-		// NodesMap[node.Name] = node
-		for _, FilteredNode := range FilterNodeInput {
-			if matched, _ := regexp.MatchString(FilteredNode, node.Name); matched {
-				filteredNodes = append(filteredNodes, node)
-			}
-		}
-	}
-
-	if len(filteredNodes) > 0 {
-		utils.Logger.Debug("Filter For Node results", filteredNodes)
-		m.Nodestats = filteredNodes
-		return m.Nodestats
-	} else {
-		utils.Logger.Errorf("No matching Nodes found.. Exiting")
-		os.Exit(2)
-		return m.Nodestats
+// sortKeys returns the sort keys the O key cycles through for the metric
+// plain keys sort by memory in the combined view
+func sortKeys(metric string) []string {
+	switch metric {
+	case "memory", "cpu":
+		return []string{"name", "usage", "free", "capacity", "request", "limit"}
+	case "disk":
+		return []string{"name", "usage", "free", "capacity"}
+	case "network":
+		return []string{"name", "usage", "rx", "tx", "free"}
+	case "all":
+		return []string{"name", "usage", "free", "cpu.usage", "cpu.free", "disk.usage", "disk.free"}
 	}
-
+	return []string{"name"}
 }
 
-func FilterForLabel(m NodeUsage) []k8s.Node {
-	var filteredNodes []k8s.Node
+// Rows returns the nodes after applying the filters and the sort from the inputs
+func Rows(m NodeUsage) []k8s.Node {
+	m.Nodestats = ApplyFilters(m)
+	SortByHandler(m)
+	return m.Nodestats
+}
 
-	FilterKey := strings.Split(m.Args.FilterLabel, "=")[0]
-	FilterValue := strings.Split(m.Args.FilterLabel, "=")[1]
+// ApplyFilters keeps the nodes matching all the filters of the inputs together
+func ApplyFilters(m NodeUsage) []k8s.Node {
+	return k8s.Filter(m.Nodestats, m.Args)
+}
 
-	if FilterKey == "" || FilterValue == "" {
-		utils.Logger.Errorf("Filter Key or Value is empty.. Exiting")
-		os.Exit(2)
+func headlinePrinter(m *NodeUsage, output *strings.Builder, Nodes *[]k8s.Node, maxNameWidth *int) {
+	unit := getUnit(m.Args.Metrics)
+	freeHeading := "Free(" + unit + ")"
+	maxHeading := "Max(" + unit + ")"
+	if m.Args.Basis == "allocatable" {
+		maxHeading = "Alloc(" + unit + ")"
 	}
+	uptimeHeading := "Uptime"
+	statusHeading := "Status"
 
-	for _, node := range m.Nodestats {
-		if _, ok := node.Labels[FilterKey]; ok {
-			if node.Labels[FilterKey] == FilterValue {
-				filteredNodes = append(filteredNodes, node)
-			}
+	// Combined view - three compact bars and no Free/Max columns
+	if m.Args.Metrics == "all" {
+		barHeading := "%-" + strconv.Itoa(compactBarWidth) + "s  "
+		if m.Args.LabelToDisplay != "" {
+			m.Format = "%-" + strconv.Itoa(*maxNameWidth) + "s %-5s %-8s %-10s %-15s %s  %s  %s\n"
+			fmt.Fprintf(output, m.Format, "Name", "Pods", uptimeHeading, statusHeading, m.Args.LabelAlias,
+				fmt.Sprintf(barHeading, "CPU%"), fmt.Sprintf(barHeading, "Memory%"), "Disk%")
+		} else {
+			m.Format = "%-" + strconv.Itoa(*maxNameWidth) + "s %-5s %-8s %-10s %s  %s  %s\n"
+			fmt.Fprintf(output, m.Format, "Name", "Pods", uptimeHeading, statusHeading,
+				fmt.Sprintf(barHeading, "CPU%"), fmt.Sprintf(barHeading, "Memory%"), "Disk%")
 		}
+		return
 	}
 
-	if len(filteredNodes) > 0 {
-		utils.Logger.Debug("Filter For Label results", filteredNodes)
-		m.Nodestats = filteredNodes
-		return m.Nodestats
-	} else {
-		utils.Logger.Errorf("No matching Nodes found.. Exiting")
-		os.Exit(2)
-		return m.Nodestats
+	// Memory and CPU show the Requested% and Limits% against allocatable next to the usage
+	// Limits% above 100 means the node is overcommitted
+	if m.Args.Metrics == "memory" || m.Args.Metrics == "cpu" {
+		if m.Args.LabelToDisplay != "" {
+			m.Format = "%-" + strconv.Itoa(*maxNameWidth) + "s %-10s %-10s %-5s %-8s %-10s %-15s %-6s %-6s %-30s\n"
+			fmt.Fprintf(output, m.Format, "Name", freeHeading, maxHeading, "Pods", uptimeHeading, statusHeading, m.Args.LabelAlias, "Req%", "Lim%", "Usage%")
+		} else {
+			m.Format = "%-" + strconv.Itoa(*maxNameWidth) + "s %-10s %-10s %-5s %-8s %-10s %-6s %-6s %-30s\n"
+			fmt.Fprintf(output, m.Format, "Name", freeHeading, maxHeading, "Pods", uptimeHeading, statusHeading, "Req%", "Lim%", "Usage%")
+		}
+		return
 	}
-}
 
-func FilterForColor(m NodeUsage) []k8s.Node {
-	utils.Logger.Debug("Filter for Color called")
-	var filteredNodes []k8s.Node
-	var thresholdMin, thresholdMax float64
-
-	// Define the color threshold values
-	switch m.Args.FilterColor {
-	case "red":
-		thresholdMin = 70
-		thresholdMax = 100
-	case "orange":
-		thresholdMin = 30
-		thresholdMax = 70
-	case "green":
-		thresholdMin = 0
-		thresholdMax = 30
-	default:
-		thresholdMin = 0
-		thresholdMax = 100
+	// Network shows the receive and transmit rates, Usage% is the busier one against the bandwidth
+	if m.Args.Metrics == "network" {
+		if m.Args.LabelToDisplay != "" {
+			m.Format = "%-" + strconv.Itoa(*maxNameWidth) + "s %-10s %-10s %-5s %-8s %-10s %-15s %-30s\n"
+			fmt.Fprintf(output, m.Format, "Name", "Rx("+unit+")", "Tx("+unit+")", "Pods", uptimeHeading, statusHeading, m.Args.LabelAlias, "Usage%")
+		} else {
+			m.Format = "%-" + strconv.Itoa(*maxNameWidth) + "s %-10s %-10s %-5s %-8s %-10s %-30s\n"
+			fmt.Fprintf(output, m.Format, "Name", "Rx("+unit+")", "Tx("+unit+")", "Pods", uptimeHeading, statusHeading, "Usage%")
+		}
+		return
 	}
 
-	// Filter nodes based on metric and threshold values
-	for _, node := range m.Nodestats {
-		var usagepercent float64
-		switch m.Args.Metrics {
-		case "memory":
-			usagepercent = float64(node.Usage_memory_percent) / 100.0
-		case "cpu":
-			usagepercent = float64(node.Usage_cpu_percent) / 100.0
-		case "disk":
-			usagepercent = float64(node.Usage_disk_percent) / 100.0
-		default:
-			if m.Args.Debug {
-				fmt.Println("No Matching Metric", m.Args.Metrics)
-			}
+	// Disk detail splits the usage into the root filesystem, the image filesystem and the logs
+	// with the inode usage next to it, inode exhaustion fills a node as surely as bytes do
+	if m.Args.Metrics == "disk" && m.Args.DiskDetail {
+		if m.Args.LabelToDisplay != "" {
+			m.Format = "%-" + strconv.Itoa(*maxNameWidth) + "s %-5s %-10s %-15s %-16s %-13s %-13s %-9s %-9s %-8s %-30s\n"
+			fmt.Fprintf(output, m.Format, "Name", "Pods", statusHeading, m.Args.LabelAlias, "Source", "Rootfs(GB)", "Imagefs(GB)", "Logs(GB)", "Imagefs%", "Inodes%", "Rootfs%")
+		} else {
+			m.Format = "%-" + strconv.Itoa(*maxNameWidth) + "s %-5s %-10s %-16s %-13s %-13s %-9s %-9s %-8s %-30s\n"
+			fmt.Fprintf(output, m.Format, "Name", "Pods", statusHeading, "Source", "Rootfs(GB)", "Imagefs(GB)", "Logs(GB)", "Imagefs%", "Inodes%", "Rootfs%")
 		}
+		return
+	}
 
-		if (usagepercent*100) >= thresholdMin && (usagepercent*100) < thresholdMax {
-			filteredNodes = append(filteredNodes, node)
+	// Disk shows where the usage comes from, kubelet-summary or unavailable
+	if m.Args.Metrics == "disk" {
+		if m.Args.LabelToDisplay != "" {
+			m.Format = "%-" + strconv.Itoa(*maxNameWidth) + "s %-10s %-10s %-5s %-8s %-10s %-15s %-16s %-30s\n"
+			fmt.Fprintf(output, m.Format, "Name", freeHeading, maxHeading, "Pods", uptimeHeading, statusHeading, m.Args.LabelAlias, "Source", "Usage%")
+		} else {
+			m.Format = "%-" + strconv.Itoa(*maxNameWidth) + "s %-10s %-10s %-5s %-8s %-10s %-16s %-30s\n"
+			fmt.Fprintf(output, m.Format, "Name", freeHeading, maxHeading, "Pods", uptimeHeading, statusHeading, "Source", "Usage%")
 		}
+		return
 	}
-	if m.Args.Debug {
-		fmt.Println("Filter For Color result:", filteredNodes)
-	}
-	return filteredNodes
-
-}
-
-func headlinePrinter(m *NodeUsage, output *strings.Builder, Nodes *[]k8s.Node, maxNameWidth *int) {
-	unit := getUnit(m.Args.Metrics)
-	freeHeading := "Free(" + unit + ")"
-	maxHeading := "Max(" + unit + ")"
-	uptimeHeading := "Uptime"
-	statusHeading := "Status"
 
 	if m.Args.LabelToDisplay != "" {
 		m.Format = "%-" + strconv.Itoa(*maxNameWidth) + "s %-10s %-10s %-5s %-8s %-10s %-15s %-30s\n"
@@ -227,6 +243,39 @@ func headlinePrinter(m *NodeUsage, output *strings.Builder, Nodes *[]k8s.Node, m
 	}
 }
 
+// diskState is shown after the disk or network bar when the kubelet of the node did not answer
+// stale means the bar is from the last summary we got, unreachable means we never got one
+func diskState(node k8s.Node) string {
+	if node.Disk_state == "" {
+		return ""
+	}
+	return " " + staleStyle.Render(node.Disk_state)
+}
+
+// diskMarker is shown after the disk bar of the combined view, which has no Source column
+// it is the kubelet state or the source when the usage is not from the kubelet summary
+func diskMarker(node k8s.Node) string {
+	if node.Disk_state != "" || node.Disk_source == "" || node.Disk_source == k8s.SourceKubelet {
+		return diskState(node)
+	}
+	return " " + staleStyle.Render(node.Disk_source)
+}
+
+// gigabytes formats bytes as GB with one decimal
+func gigabytes(bytes int) string {
+	return fmt.Sprintf("%.1f", float64(bytes)/float64(1024*1024*1024))
+}
+
+// compactBarWidth is the width of each bar in the combined view
+const compactBarWidth = 22
+
+// compactBar renders a narrow bar so cpu, memory and disk fit on one row
+func compactBar(usage float64) string {
+	prog := common.GetBar(usage)
+	prog.Width = compactBarWidth
+	return prog.ViewAs(usage)
+}
+
 func PrintDesign(output *strings.Builder, maxNameWidth int) {
 	if maxNameWidth < 30 {
 		maxNameWidth = 30
@@ -236,13 +285,11 @@ func PrintDesign(output *strings.Builder, maxNameWidth int) {
 	fmt.Fprint(output, "\n")
 }
 
-func MetricsHandler(m NodeUsage, output *strings.Builder) {
+// MetricsHandler writes the node table and returns the nodes in the order they are written
+func MetricsHandler(m NodeUsage, output *strings.Builder) []k8s.Node {
 
-	// Nodes Filtering based on filters
-	filteredNodes := ApplyFilters(m)
-
-	m.Nodestats = filteredNodes
-	SortByHandler(m)
+	// Nodes Filtering and Sorting based on the inputs
+	filteredNodes := Rows(m)
 
 	// decide formatting and Maximum width
 	maxNameWidth := 30
@@ -258,83 +305,233 @@ func MetricsHandler(m NodeUsage, output *strings.Builder) {
 		fmt.Fprint(output, "\n# Context: ", m.ClusterInfo.Context, "\n# Version: ", m.ClusterInfo.Version, "\n# URL: ", m.ClusterInfo.URL, "\n\n")
 	}
 
-	fmt.Fprint(output, "# ", strcase.ToCamel(m.Args.Metrics), " Metrics\n\n")
+	fmt.Fprint(output, "# ", strcase.ToCamel(m.Args.Metrics), " Metrics\n# Sorted by: ", utils.SortIndicator(m.Args.SortBy, m.Args.ReverseFlag),
+		"\n# Last updated: ", utils.RefreshIndicator(m.updated, m.interval(), m.paused), "\n\n")
 	headlinePrinter(&m, output, &filteredNodes, &maxNameWidth)
 	PrintDesign(output, maxNameWidth)
 
 	if m.Args.Metrics == "memory" {
 		for _, node := range filteredNodes {
-			prog := GetBar(float64(node.Usage_memory_percent) / 100.0)
+			prog := common.GetBar(float64(node.Usage_memory_percent) / 100.0)
+			maxMemory, _, _ := node.Max(m.Args.Basis)
 			if m.Args.LabelToDisplay != "" {
 				fmt.Fprintf(output, m.Format,
 					node.Name,
 					strconv.Itoa(node.Free_memory/1024),
-					strconv.Itoa(node.Capacity_memory/1024),
+					strconv.Itoa(maxMemory/1024),
 					node.TotalPods,
 					node.Uptime,
 					node.Status,
 					node.LabelToDisplay,
+					fmt.Sprintf("%.0f", node.Request_memory_percent),
+					fmt.Sprintf("%.0f", node.Limit_memory_percent),
 					prog.ViewAs(float64(node.Usage_memory_percent)/100.0))
 			} else {
 				fmt.Fprintf(output, m.Format,
 					node.Name,
 					strconv.Itoa(node.Free_memory/1024),
-					strconv.Itoa(node.Capacity_memory/1024),
+					strconv.Itoa(maxMemory/1024),
 					node.TotalPods,
 					node.Uptime,
 					node.Status,
+					fmt.Sprintf("%.0f", node.Request_memory_percent),
+					fmt.Sprintf("%.0f", node.Limit_memory_percent),
 					prog.ViewAs(float64(node.Usage_memory_percent)/100.0))
 			}
 		}
 	} else if m.Args.Metrics == "cpu" {
 		for _, node := range filteredNodes {
-			prog := GetBar(float64(node.Usage_cpu_percent) / 100.0)
+			prog := common.GetBar(float64(node.Usage_cpu_percent) / 100.0)
+			_, maxCpu, _ := node.Max(m.Args.Basis)
 			if m.Args.LabelToDisplay != "" {
 				fmt.Fprintf(output, m.Format,
 					node.Name,
 					strconv.Itoa(int(node.Free_cpu)),
-					strconv.Itoa(node.Capacity_cpu),
+					strconv.Itoa(maxCpu),
 					node.TotalPods,
 					node.Uptime,
 					node.Status,
 					node.LabelToDisplay,
+					fmt.Sprintf("%.0f", node.Request_cpu_percent),
+					fmt.Sprintf("%.0f", node.Limit_cpu_percent),
 					prog.ViewAs(float64(node.Usage_cpu_percent)/100.0))
 			} else {
 				fmt.Fprintf(output, m.Format,
 					node.Name,
 					strconv.Itoa(int(node.Free_cpu)),
-					strconv.Itoa(node.Capacity_cpu),
+					strconv.Itoa(maxCpu),
 					node.TotalPods,
 					node.Uptime,
 					node.Status,
+					fmt.Sprintf("%.0f", node.Request_cpu_percent),
+					fmt.Sprintf("%.0f", node.Limit_cpu_percent),
 					prog.ViewAs(float64(node.Usage_cpu_percent)/100.0))
 			}
 		}
+	} else if m.Args.Metrics == "all" {
+		for _, node := range filteredNodes {
+			cpuBar := compactBar(float64(node.Usage_cpu_percent) / 100.0)
+			memoryBar := compactBar(float64(node.Usage_memory_percent) / 100.0)
+			diskBar := compactBar(float64(node.Usage_disk_percent) / 100.0)
+			if m.Args.LabelToDisplay != "" {
+				fmt.Fprintf(output, m.Format,
+					node.Name,
+					node.TotalPods,
+					node.Uptime,
+					node.Status,
+					node.LabelToDisplay,
+					cpuBar, memoryBar, diskBar+diskMarker(node))
+			} else {
+				fmt.Fprintf(output, m.Format,
+					node.Name,
+					node.TotalPods,
+					node.Uptime,
+					node.Status,
+					cpuBar, memoryBar, diskBar+diskMarker(node))
+			}
+		}
+	} else if m.Args.Metrics == "network" {
+		for _, node := range filteredNodes {
+			prog := common.GetBar(float64(node.Usage_network_percent) / 100.0)
+			if m.Args.LabelToDisplay != "" {
+				fmt.Fprintf(output, m.Format,
+					node.Name,
+					fmt.Sprintf("%.1f", node.Rx_network/1024),
+					fmt.Sprintf("%.1f", node.Tx_network/1024),
+					node.TotalPods,
+					node.Uptime,
+					node.Status,
+					node.LabelToDisplay,
+					prog.ViewAs(float64(node.Usage_network_percent)/100.0)+diskState(node))
+			} else {
+				fmt.Fprintf(output, m.Format,
+					node.Name,
+					fmt.Sprintf("%.1f", node.Rx_network/1024),
+					fmt.Sprintf("%.1f", node.Tx_network/1024),
+					node.TotalPods,
+					node.Uptime,
+					node.Status,
+					prog.ViewAs(float64(node.Usage_network_percent)/100.0)+diskState(node))
+			}
+		}
+	} else if m.Args.Metrics == "disk" && m.Args.DiskDetail {
+		for _, node := range filteredNodes {
+			prog := common.GetBar(float64(node.Usage_disk_percent) / 100.0)
+			rootfs, imagefs, logs := "NA", "NA", "NA"
+			imagefsPercent, inodesPercent := "NA", "NA"
+			if node.Disk_source != k8s.SourceUnavailable {
+				rootfs = gigabytes(node.Usage_disk) + "/" + gigabytes(node.Capacity_disk)
+			}
+			// The breakdown only comes with the kubelet summary
+			if node.Disk_source == k8s.SourceKubelet {
+				imagefs = gigabytes(node.Usage_imagefs) + "/" + gigabytes(node.Capacity_imagefs)
+				logs = gigabytes(node.Usage_logs)
+				imagefsPercent = fmt.Sprintf("%.0f", node.Usage_imagefs_percent)
+				inodesPercent = fmt.Sprintf("%.0f", node.Usage_inodes_percent)
+			}
+			if m.Args.LabelToDisplay != "" {
+				fmt.Fprintf(output, m.Format,
+					node.Name,
+					node.TotalPods,
+					node.Status,
+					node.LabelToDisplay,
+					node.Disk_source,
+					rootfs, imagefs, logs,
+					imagefsPercent, inodesPercent,
+					prog.ViewAs(float64(node.Usage_disk_percent)/100.0)+diskState(node))
+			} else {
+				fmt.Fprintf(output, m.Format,
+					node.Name,
+					node.TotalPods,
+					node.Status,
+					node.Disk_source,
+					rootfs, imagefs, logs,
+					imagefsPercent, inodesPercent,
+					prog.ViewAs(float64(node.Usage_disk_percent)/100.0)+diskState(node))
+			}
+		}
 	} else if m.Args.Metrics == "disk" {
 		for _, node := range filteredNodes {
-			prog := GetBar(float64(node.Usage_disk_percent) / 100.0)
+			prog := common.GetBar(float64(node.Usage_disk_percent) / 100.0)
+			_, _, maxDisk := node.Max(m.Args.Basis)
 			// Convert bytes to GB (1 GB = 1024^3 bytes)
 			gbDivisor := 1024 * 1024 * 1024
+			free := fmt.Sprintf("%.1f", float64(node.Free_disk)/float64(gbDivisor))
+			if node.Disk_source == k8s.SourceUnavailable {
+				free = "NA"
+			}
 			if m.Args.LabelToDisplay != "" {
 				fmt.Fprintf(output, m.Format,
 					node.Name,
-					fmt.Sprintf("%.1f", float64(node.Free_disk)/float64(gbDivisor)),
-					fmt.Sprintf("%.1f", float64(node.Capacity_disk)/float64(gbDivisor)),
+					free,
+					fmt.Sprintf("%.1f", float64(maxDisk)/float64(gbDivisor)),
 					node.TotalPods,
 					node.Uptime,
 					node.Status,
 					node.LabelToDisplay,
-					prog.ViewAs(float64(node.Usage_disk_percent)/100.0))
+					node.Disk_source,
+					prog.ViewAs(float64(node.Usage_disk_percent)/100.0)+diskState(node))
 			} else {
 				fmt.Fprintf(output, m.Format,
 					node.Name,
-					fmt.Sprintf("%.1f", float64(node.Free_disk)/float64(gbDivisor)),
-					fmt.Sprintf("%.1f", float64(node.Capacity_disk)/float64(gbDivisor)),
+					free,
+					fmt.Sprintf("%.1f", float64(maxDisk)/float64(gbDivisor)),
 					node.TotalPods,
 					node.Uptime,
 					node.Status,
-					prog.ViewAs(float64(node.Usage_disk_percent)/100.0))
+					node.Disk_source,
+					prog.ViewAs(float64(node.Usage_disk_percent)/100.0)+diskState(node))
 			}
 		}
 	}
+
+	return filteredNodes
+}
+
+// detailLines describes the selected node for the detail pane
+// usage is only known for the metric on screen, the requests and limits are there for every metric
+func detailLines(m NodeUsage, node k8s.Node) []string {
+	var lines []string
+	lines = append(lines, fmt.Sprintf("Node: %s   Status: %s   Uptime: %s   Pods: %s", node.Name, node.Status, node.Uptime, node.TotalPods))
+
+	cpuUsage, memoryUsage := "NA", "NA"
+	if m.Args.Metrics == "cpu" || m.Args.Metrics == "all" {
+		cpuUsage = fmt.Sprintf("%.2f (%.0f%%)", node.Usage_cpu/1000, node.Usage_cpu_percent)
+	}
+	if m.Args.Metrics == "memory" || m.Args.Metrics == "all" {
+		memoryUsage = fmt.Sprintf("%s (%.0f%%)", gigabytes(node.Usage_memory*1024), node.Usage_memory_percent)
+	}
+	lines = append(lines, fmt.Sprintf("%-12s Usage %-14s Requests %-14s Limits %-14s Allocatable %.2f",
+		"CPU(Cores)", cpuUsage,
+		fmt.Sprintf("%.2f (%.0f%%)", node.Request_cpu/1000, node.Request_cpu_percent),
+		fmt.Sprintf("%.2f (%.0f%%)", node.Limit_cpu/1000, node.Limit_cpu_percent),
+		float32(node.Allocatable_cpu)/1000))
+	lines = append(lines, fmt.Sprintf("%-12s Usage %-14s Requests %-14s Limits %-14s Allocatable %s",
+		"Memory(GB)", memoryUsage,
+		fmt.Sprintf("%s (%.0f%%)", gigabytes(node.Request_memory*1024), node.Request_memory_percent),
+		fmt.Sprintf("%s (%.0f%%)", gigabytes(node.Limit_memory*1024), node.Limit_memory_percent),
+		gigabytes(node.Allocatable_memory*1024)))
+
+	var conditions []string
+	for _, condition := range node.Conditions {
+		conditions = append(conditions, condition.Type+"="+condition.Status)
+	}
+	lines = append(lines, fmt.Sprintf("%-12s %s", "Conditions", strings.Join(conditions, "  ")))
+	lines = append(lines, fmt.Sprintf("%-12s %s", "Labels", labelList(node.Labels)))
+	return lines
+}
+
+// labelList returns the labels as key=value sorted by key
+func labelList(labels map[string]string) string {
+	keys := make([]string, 0, len(labels))
+	for key := range labels {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	pairs := make([]string, 0, len(keys))
+	for _, key := range keys {
+		pairs = append(pairs, key+"="+labels[key])
+	}
+	return strings.Join(pairs, "  ")
 }
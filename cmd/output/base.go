@@ -0,0 +1,127 @@
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/AKSarav/KubeNodeUsage/v3/cmd/namespacemodel"
+	"github.com/AKSarav/KubeNodeUsage/v3/cmd/nodemodel"
+	"github.com/AKSarav/KubeNodeUsage/v3/cmd/podmodel"
+	"github.com/AKSarav/KubeNodeUsage/v3/cmd/volumemodel"
+	"github.com/AKSarav/KubeNodeUsage/v3/k8s"
+	"github.com/AKSarav/KubeNodeUsage/v3/utils"
+
+	"sigs.k8s.io/yaml"
+)
+
+// networkWarmup is how long we wait between the two samples the network rates are calculated from
+// the kubelet refreshes its network counters every 10 seconds or so
+const networkWarmup = 15 * time.Second
+
+// Run collects the nodes, pods or namespaces once, applies the same filters and sort as the TUI
+// and prints them to stdout in the format chosen with --output
+func Run(args *utils.Inputs, collector *k8s.Collector) error {
+	// The rates need a previous sample, take one and wait for the counters to move
+	if args.Metrics == "network" {
+		var err error
+		if args.Pods {
+			_, err = collector.Pods()
+		} else {
+			_, err = collector.Nodes()
+		}
+		if err != nil {
+			return err
+		}
+		utils.Logger.Info("Sampling the network counters for ", networkWarmup)
+		time.Sleep(networkWarmup)
+	}
+
+	if args.Volumes {
+		volumes, err := collector.Volumes()
+		if err != nil {
+			return err
+		}
+		rows := volumemodel.Rows(volumemodel.VolumeUsage{Args: args, Volumestats: volumes})
+		if rows == nil {
+			rows = []k8s.Volume{} // print an empty list rather than null
+		}
+		return Print(os.Stdout, args, rows, func(wide bool) ([]string, [][]string) {
+			return volumeTable(args, rows, wide)
+		})
+	}
+
+	if args.By == "namespace" {
+		pods, err := collector.Pods()
+		if err != nil {
+			return err
+		}
+		capacity, err := collector.ClusterCapacityFor(args, pods)
+		if err != nil {
+			return err
+		}
+		rows := namespacemodel.Rows(namespacemodel.NamespaceUsage{Args: args, Podstats: pods, Capacity: capacity})
+		if rows == nil {
+			rows = []k8s.Namespace{} // print an empty list rather than null
+		}
+		return Print(os.Stdout, args, rows, func(wide bool) ([]string, [][]string) {
+			return namespaceTable(args, rows, wide)
+		})
+	}
+
+	if args.Pods {
+		pods, err := collector.Pods()
+		if err != nil {
+			return err
+		}
+		rows := podmodel.Rows(podmodel.PodUsage{Args: args, Podstats: pods})
+		if rows == nil {
+			rows = []k8s.Pod{} // print an empty list rather than null
+		}
+		return Print(os.Stdout, args, rows, func(wide bool) ([]string, [][]string) {
+			return podTable(args, rows, wide)
+		})
+	}
+
+	nodes, err := collector.Nodes()
+	if err != nil {
+		return err
+	}
+	rows := nodemodel.Rows(nodemodel.NodeUsage{Args: args, Nodestats: nodes})
+	if rows == nil {
+		rows = []k8s.Node{} // print an empty list rather than null
+	}
+	return Print(os.Stdout, args, rows, func(wide bool) ([]string, [][]string) {
+		return nodeTable(args, rows, wide)
+	})
+}
+
+// Print writes the rows in the requested output format
+// json and yaml marshal the rows as they are, the other formats use the header and cells from table
+func Print(w io.Writer, args *utils.Inputs, rows interface{}, table func(wide bool) ([]string, [][]string)) error {
+	switch args.Output {
+	case "json":
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(rows)
+	case "yaml":
+		out, err := yaml.Marshal(rows)
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(out)
+		return err
+	case "csv":
+		header, cells := table(true)
+		return writeCSV(w, header, cells)
+	case "wide":
+		header, cells := table(true)
+		return writeTable(w, header, cells)
+	case "table":
+		header, cells := table(false)
+		return writeTable(w, header, cells)
+	}
+	return fmt.Errorf("invalid output format: %s", args.Output)
+}
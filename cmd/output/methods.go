@@ -0,0 +1,348 @@
+package output
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/AKSarav/KubeNodeUsage/v3/k8s"
+	"github.com/AKSarav/KubeNodeUsage/v3/utils"
+)
+
+const gbDivisor = 1024 * 1024 * 1024
+
+func writeTable(w io.Writer, header []string, cells [][]string) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, strings.Join(header, "\t"))
+	for _, row := range cells {
+		fmt.Fprintln(tw, strings.Join(row, "\t"))
+	}
+	return tw.Flush()
+}
+
+func writeCSV(w io.Writer, header []string, cells [][]string) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+	if err := cw.WriteAll(cells); err != nil {
+		return err
+	}
+	return cw.Error()
+}
+
+// joinLabels renders the labels as sorted key=value pairs
+func joinLabels(labels map[string]string) string {
+	var result []string
+	for k, v := range labels {
+		result = append(result, k+"="+v)
+	}
+	sort.Strings(result)
+	return strings.Join(result, ",")
+}
+
+func percent(value float32) string {
+	return fmt.Sprintf("%.1f", value)
+}
+
+// nodeTable returns the columns for the selected metric, the units are part of the header
+// wide adds the usage, the ready status and all the labels
+func nodeTable(args *utils.Inputs, nodes []k8s.Node, wide bool) ([]string, [][]string) {
+	// Combined view - one usage column per metric
+	if args.Metrics == "all" {
+		return nodeTableAll(args, nodes, wide)
+	}
+
+	var unit string
+	switch args.Metrics {
+	case "memory":
+		unit = "MB"
+	case "cpu":
+		unit = "Cores"
+	case "disk":
+		unit = "GB"
+	case "network":
+		unit = "KB/s"
+	}
+
+	maxHeading := "Max(" + unit + ")"
+	if args.Basis == "allocatable" {
+		maxHeading = "Alloc(" + unit + ")"
+	}
+
+	header := []string{"Name", "Free(" + unit + ")", maxHeading}
+	if wide {
+		header = append(header, "Usage("+unit+")")
+	}
+	if args.Metrics == "network" {
+		// The rates take the place of Free and Max, the bandwidth of Usage
+		header = []string{"Name", "Rx(" + unit + ")", "Tx(" + unit + ")"}
+		if wide {
+			header = append(header, "Max("+unit+")")
+		}
+	}
+	header = append(header, "Pods", "Uptime", "Status")
+	if args.LabelToDisplay != "" {
+		header = append(header, args.LabelAlias)
+	}
+	if args.Metrics == "memory" || args.Metrics == "cpu" {
+		header = append(header, "Req%", "Lim%")
+	}
+	if args.Metrics == "disk" {
+		header = append(header, "Source")
+	}
+	if args.DiskDetail {
+		header = append(header, "Imagefs(GB)", "Logs(GB)", "Imagefs%", "Inodes%")
+	}
+	header = append(header, "Usage%")
+	if wide {
+		header = append(header, "Labels")
+	}
+
+	var cells [][]string
+	for _, node := range nodes {
+		var free, max, usage string
+		var usagePercent, requestPercent, limitPercent float32
+		maxMemory, maxCpu, maxDisk := node.Max(args.Basis)
+		switch args.Metrics {
+		case "memory":
+			free = fmt.Sprint(node.Free_memory / 1024)
+			max = fmt.Sprint(maxMemory / 1024)
+			usage = fmt.Sprint(node.Usage_memory / 1024)
+			usagePercent = node.Usage_memory_percent
+			requestPercent = node.Request_memory_percent
+			limitPercent = node.Limit_memory_percent
+		case "cpu":
+			free = fmt.Sprintf("%.2f", node.Free_cpu/1000)
+			max = fmt.Sprintf("%.2f", float32(maxCpu)/1000)
+			usage = fmt.Sprintf("%.2f", node.Usage_cpu/1000)
+			usagePercent = node.Usage_cpu_percent
+			requestPercent = node.Request_cpu_percent
+			limitPercent = node.Limit_cpu_percent
+		case "disk":
+			free = fmt.Sprintf("%.1f", float64(node.Free_disk)/gbDivisor)
+			max = fmt.Sprintf("%.1f", float64(maxDisk)/gbDivisor)
+			usage = fmt.Sprintf("%.1f", float64(node.Usage_disk)/gbDivisor)
+			usagePercent = node.Usage_disk_percent
+			if maxDisk <= 0 {
+				max = "NA"
+			}
+			if node.Disk_source == k8s.SourceUnavailable {
+				free, usage = "NA", "NA"
+			}
+		case "network":
+			free = fmt.Sprintf("%.1f", node.Rx_network/1024)
+			max = fmt.Sprintf("%.1f", node.Tx_network/1024)
+			usage = fmt.Sprint(node.Capacity_network / 1024)
+			usagePercent = node.Usage_network_percent
+		}
+
+		row := []string{node.Name, free, max}
+		if wide {
+			row = append(row, usage)
+		}
+		row = append(row, node.TotalPods, node.Uptime, node.Status)
+		if args.LabelToDisplay != "" {
+			row = append(row, node.LabelToDisplay)
+		}
+		if args.Metrics == "memory" || args.Metrics == "cpu" {
+			row = append(row, percent(requestPercent), percent(limitPercent))
+		}
+		if args.Metrics == "disk" {
+			row = append(row, node.Disk_source)
+		}
+		if args.DiskDetail && node.Disk_source != k8s.SourceKubelet {
+			// The breakdown only comes with the kubelet summary
+			row = append(row, "NA", "NA", "NA", "NA")
+		} else if args.DiskDetail {
+			row = append(row,
+				fmt.Sprintf("%.1f", float64(node.Usage_imagefs)/gbDivisor),
+				fmt.Sprintf("%.1f", float64(node.Usage_logs)/gbDivisor),
+				percent(node.Usage_imagefs_percent),
+				percent(node.Usage_inodes_percent))
+		}
+		if args.Metrics == "disk" && node.Disk_source == k8s.SourceUnavailable {
+			row = append(row, "NA")
+		} else {
+			row = append(row, percent(usagePercent))
+		}
+		if wide {
+			row = append(row, joinLabels(node.Labels))
+		}
+		cells = append(cells, row)
+	}
+	return header, cells
+}
+
+// nodeTableAll returns the cpu, memory and disk usage side by side
+// wide adds the free cpu, memory and disk and all the labels
+func nodeTableAll(args *utils.Inputs, nodes []k8s.Node, wide bool) ([]string, [][]string) {
+	header := []string{"Name", "Pods", "Uptime", "Status"}
+	if args.LabelToDisplay != "" {
+		header = append(header, args.LabelAlias)
+	}
+	header = append(header, "CPU%", "Memory%", "Disk%")
+	if wide {
+		header = append(header, "Free CPU(Cores)", "Free Memory(MB)", "Free Disk(GB)", "Labels")
+	}
+
+	var cells [][]string
+	for _, node := range nodes {
+		row := []string{node.Name, node.TotalPods, node.Uptime, node.Status}
+		if args.LabelToDisplay != "" {
+			row = append(row, node.LabelToDisplay)
+		}
+		diskPercent, freeDisk := percent(node.Usage_disk_percent), fmt.Sprintf("%.1f", float64(node.Free_disk)/gbDivisor)
+		if node.Disk_source == k8s.SourceUnavailable {
+			diskPercent, freeDisk = "NA", "NA"
+		}
+		row = append(row, percent(node.Usage_cpu_percent), percent(node.Usage_memory_percent), diskPercent)
+		if wide {
+			row = append(row,
+				fmt.Sprintf("%.2f", node.Free_cpu/1000),
+				fmt.Sprint(node.Free_memory/1024),
+				freeDisk,
+				joinLabels(node.Labels))
+		}
+		cells = append(cells, row)
+	}
+	return header, cells
+}
+
+// podTable returns the columns for the selected metric, the units are part of the header
+// wide adds the pod phase and all the labels
+func podTable(args *utils.Inputs, pods []k8s.Pod, wide bool) ([]string, [][]string) {
+	var header []string
+	if args.Metrics == "disk" {
+		header = []string{"Name", "Namespace", "Node", "Usage(MB)", "Node Cap(GB)", "Source"}
+	} else if args.Metrics == "network" {
+		header = []string{"Name", "Namespace", "Node", "Rx(KB/s)", "Tx(KB/s)"}
+	} else {
+		unit := "MB"
+		if args.Metrics == "cpu" {
+			unit = "Cores"
+		}
+		header = []string{"Name", "Namespace", "Node", "Usage(" + unit + ")", "Request(" + unit + ")", "Limit(" + unit + ")"}
+	}
+	if wide {
+		header = append(header, "Status")
+	}
+	if args.LabelToDisplay != "" {
+		header = append(header, args.LabelAlias)
+	}
+	if args.Metrics != "disk" {
+		header = append(header, "Usage%")
+	}
+	if wide {
+		header = append(header, "Labels")
+	}
+
+	var cells [][]string
+	for _, pod := range pods {
+		row := []string{pod.Name, pod.Namespace, pod.NodeName}
+		switch args.Metrics {
+		case "memory":
+			row = append(row, fmt.Sprint(pod.Usage_memory), fmt.Sprint(pod.Request_memory), fmt.Sprint(pod.Limit_memory))
+		case "cpu":
+			row = append(row, fmt.Sprintf("%.2f", pod.Usage_cpu), fmt.Sprintf("%.2f", pod.Request_cpu), fmt.Sprintf("%.2f", pod.Limit_cpu))
+		case "disk":
+			usage := fmt.Sprintf("%.2f", pod.Usage_disk)
+			if pod.Disk_source == k8s.SourceUnavailable {
+				usage = "NA"
+			}
+			row = append(row, usage, fmt.Sprintf("%.1f", pod.Node_disk_capacity), pod.Disk_source)
+		case "network":
+			row = append(row, fmt.Sprintf("%.1f", pod.Rx_network/1024), fmt.Sprintf("%.1f", pod.Tx_network/1024))
+		}
+		if wide {
+			row = append(row, pod.Status)
+		}
+		if args.LabelToDisplay != "" {
+			row = append(row, pod.LabelToDisplay)
+		}
+		switch args.Metrics {
+		case "memory":
+			row = append(row, percent(pod.Usage_memory_percent))
+		case "cpu":
+			row = append(row, percent(pod.Usage_cpu_percent))
+		case "network":
+			row = append(row, percent(pod.Usage_network_percent))
+		}
+		if wide {
+			row = append(row, joinLabels(pod.Labels))
+		}
+		cells = append(cells, row)
+	}
+	return header, cells
+}
+
+// volumeTable returns the used and capacity of every volume in GB
+// wide adds the free space and the labels of the pod
+func volumeTable(args *utils.Inputs, volumes []k8s.Volume, wide bool) ([]string, [][]string) {
+	header := []string{"Volume", "Pod", "Namespace", "Node", "Type", "PVC", "Used(GB)", "Cap(GB)"}
+	if wide {
+		header = append(header, "Free(GB)")
+	}
+	if args.LabelToDisplay != "" {
+		header = append(header, args.LabelAlias)
+	}
+	header = append(header, "Usage%")
+	if wide {
+		header = append(header, "Labels")
+	}
+
+	var cells [][]string
+	for _, volume := range volumes {
+		row := []string{volume.Name, volume.Pod, volume.Namespace, volume.NodeName, volume.Type, volume.PVC,
+			fmt.Sprintf("%.2f", float64(volume.Usage_volume)/gbDivisor),
+			fmt.Sprintf("%.2f", float64(volume.Capacity_volume)/gbDivisor)}
+		if wide {
+			row = append(row, fmt.Sprintf("%.2f", float64(volume.Free_volume)/gbDivisor))
+		}
+		if args.LabelToDisplay != "" {
+			row = append(row, volume.LabelToDisplay)
+		}
+		row = append(row, percent(volume.Usage_volume_percent))
+		if wide {
+			row = append(row, joinLabels(volume.Labels))
+		}
+		cells = append(cells, row)
+	}
+	return header, cells
+}
+
+// namespaceTable returns the pods summed up per namespace for the selected metric, the units are part of the header
+// wide adds the usage of the other metric
+func namespaceTable(args *utils.Inputs, namespaces []k8s.Namespace, wide bool) ([]string, [][]string) {
+	unit := "MB"
+	if args.Metrics == "cpu" {
+		unit = "Cores"
+	}
+	header := []string{"Namespace", "Pods", "Usage(" + unit + ")", "Request(" + unit + ")", "Limit(" + unit + ")", "Cluster(" + unit + ")", "Usage%"}
+	if wide {
+		header = append(header, "Memory(MB)", "Memory%", "Cpu(Cores)", "Cpu%")
+	}
+
+	var cells [][]string
+	for _, namespace := range namespaces {
+		row := []string{namespace.Name, fmt.Sprint(namespace.Pods)}
+		switch args.Metrics {
+		case "memory":
+			row = append(row, fmt.Sprint(namespace.Usage_memory), fmt.Sprint(namespace.Request_memory),
+				fmt.Sprint(namespace.Limit_memory), fmt.Sprint(namespace.Capacity_memory))
+		case "cpu":
+			row = append(row, fmt.Sprintf("%.2f", namespace.Usage_cpu), fmt.Sprintf("%.2f", namespace.Request_cpu),
+				fmt.Sprintf("%.2f", namespace.Limit_cpu), fmt.Sprintf("%.2f", namespace.Capacity_cpu))
+		}
+		row = append(row, percent(namespace.UsagePercent(args.Metrics)))
+		if wide {
+			row = append(row, fmt.Sprint(namespace.Usage_memory), percent(namespace.Usage_memory_percent),
+				fmt.Sprintf("%.2f", namespace.Usage_cpu), percent(namespace.Usage_cpu_percent))
+		}
+		cells = append(cells, row)
+	}
+	return header, cells
+}
@@ -5,27 +5,41 @@ import (
 	"strings"
 	"time"
 
+	"github.com/AKSarav/KubeNodeUsage/v3/cmd/common"
 	"github.com/AKSarav/KubeNodeUsage/v3/k8s"
 	"github.com/AKSarav/KubeNodeUsage/v3/utils"
 
-	"github.com/charmbracelet/bubbles/progress"
+	"github.com/charmbracelet/bubbles/spinner"
 	"github.com/charmbracelet/bubbles/textinput"
 	"github.com/charmbracelet/bubbles/viewport"
 	tea "github.com/charmbracelet/bubbletea"
-	"github.com/charmbracelet/lipgloss"
 )
 
-var (
-	helpStyle   = lipgloss.NewStyle().Foreground(lipgloss.Color("#626262")).Render
-	searchStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("#FFFF00")).Bold(true)
-	// highlightStyle = lipgloss.NewStyle().Background(lipgloss.Color("#fff0f4"))
-)
+// tickMsg carries the session of the model that asked for it
+// so the ticks of a closed drill-down do not drive the next one
+type tickMsg struct {
+	session int
+}
+
+// statsMsg carries the pods fetched in the background for the model of the session
+// fetch tells which fetch it answers, the result of a superseded fetch is dropped
+type statsMsg struct {
+	session int
+	fetch   int
+	stats   []k8s.Pod
+	err     error
+}
 
-type tickMsg time.Time
+// sessions counts the models created, every model has its own tick loop
+var sessions int
+
+// refreshInterval is the delay between refreshes when the API calls succeed
+const refreshInterval = time.Second * 5
 
 // podusage is the Bubble Tea model.
 type PodUsage struct {
 	ClusterInfo k8s.Cluster
+	Collector   *k8s.Collector
 	Podstats    []k8s.Pod
 	Args        *utils.Inputs
 	Format      string
@@ -38,10 +52,28 @@ type PodUsage struct {
 	maxWidth    int // Maximum content width
 	searchInput textinput.Model
 	searching   bool
+	err         error  // last refresh error, shown as a banner while the last good data stays on screen
+	failures    int    // consecutive refresh failures, used for the retry backoff
+	Node        string // only the pods of this node when opened from the node view
+	namespace   string // only the pods of this namespace, chosen with the N key
+	session     int
+	rows        []k8s.Pod // the pods in the order they are shown
+	cursor      int       // selected row among the visible ones
+	detail      bool      // detail pane of the selected pod
+	paused      bool      // no refreshes while reading a frozen snapshot
+	updated     time.Time // when the data on screen was fetched
+	fetching    bool      // a fetch is running, the refreshes asked meanwhile are dropped
+	fetches     int       // fetches started, the view waits for the last one
+	spinner     spinner.Model
 }
 
 // NewPodUsage creates a new PodUsage model
-func NewPodUsage(args *utils.Inputs) PodUsage {
+func NewPodUsage(args *utils.Inputs, collector *k8s.Collector) PodUsage {
+	return NewNodePodUsage(args, collector, "")
+}
+
+// NewNodePodUsage creates a PodUsage model showing the pods of a single node
+func NewNodePodUsage(args *utils.Inputs, collector *k8s.Collector, node string) PodUsage {
 	ti := textinput.New()
 	ti.Placeholder = "Search..."
 	ti.CharLimit = 156
@@ -50,8 +82,8 @@ func NewPodUsage(args *utils.Inputs) PodUsage {
 	model := PodUsage{
 		Args:        args,
 		searchInput: ti,
-		ClusterInfo: k8s.ClusterInfo(),
-		Podstats:    k8s.Pods(args),
+		Collector:   collector,
+		ClusterInfo: collector.ClusterInfo(),
 		content:     "",
 		xOffset:     0,
 		width:       0,
@@ -59,31 +91,161 @@ func NewPodUsage(args *utils.Inputs) PodUsage {
 		ready:       false,
 		maxWidth:    0,
 		searching:   false,
+		Node:        node,
+		spinner:     spinner.New(spinner.WithSpinner(spinner.Dot)),
 	}
+	sessions++
+	model.session = sessions
+
+	// The initial fetch is started by Init, the model starts empty
+	model.fetches = 1
+	model.fetching = true
 
 	// Initialize content
+	model.render()
+
+	return model
+}
+
+// render redraws the content from the current stats and keeps the rows in the order they are shown
+func (m *PodUsage) render() {
 	var output strings.Builder
-	MetricsHandler(model, &output)
-	model.content = output.String()
+	m.rows = MetricsHandler(*m, &output)
+	m.content = output.String()
 
-	// Calculate initial maxWidth
-	for _, line := range strings.Split(model.content, "\n") {
-		if len(line) > model.maxWidth {
-			model.maxWidth = len(line)
+	m.maxWidth = 0
+	for _, line := range strings.Split(m.content, "\n") {
+		if len(line) > m.maxWidth {
+			m.maxWidth = len(line)
 		}
 	}
+}
 
-	return model
+// visible splits the content into the header and the row lines left after the search
+// and returns the pods of those lines in the same order
+func (m PodUsage) visible() (header []string, lines []string, rows []k8s.Pod) {
+	searchTerm := ""
+	if m.searching {
+		searchTerm = m.searchInput.Value()
+	}
+	return common.Visible(m.content, m.rows, searchTerm)
+}
+
+// Selected returns the pod under the cursor
+func (m PodUsage) Selected() (k8s.Pod, bool) {
+	_, _, rows := m.visible()
+	if m.cursor < 0 || m.cursor >= len(rows) {
+		return k8s.Pod{}, false
+	}
+	return rows[m.cursor], true
+}
+
+// moveCursor moves the selection by delta rows and scrolls the viewport to keep it in sight
+func (m *PodUsage) moveCursor(delta int) {
+	header, _, rows := m.visible()
+	m.cursor = common.ClampCursor(m.cursor+delta, len(rows))
+
+	// Only a key press scrolls, a refresh leaves the viewport where it is
+	if delta != 0 {
+		m.scrollTo(len(header) + m.cursor)
+	}
+}
+
+// scrollTo scrolls the viewport just enough to show the given line above the detail pane
+func (m *PodUsage) scrollTo(line int) {
+	common.ScrollTo(&m.viewport, m.bodyHeight(), line)
+}
+
+// detailPane renders the detail of the selected pod, empty when the pane is closed
+// it takes at most half of the screen, long label lists wrap within it
+func (m PodUsage) detailPane() string {
+	pod, ok := m.Selected()
+	if !m.detail || !ok {
+		return ""
+	}
+	return common.DetailPane(detailLines(pod), m.width, m.height)
+}
+
+// bodyHeight is the height left to the viewport by the detail pane, the error banner and the help line
+func (m PodUsage) bodyHeight() int {
+	return common.BodyHeight(m.height, m.err != nil, m.detailPane())
+}
+
+// refresh fetches the pods again in the background unless a fetch is already running
+func (m *PodUsage) refresh() tea.Cmd {
+	if m.fetching {
+		return nil
+	}
+	return m.fetch()
+}
+
+// fetch starts a fetch of the pods, superseding the one running if any
+func (m *PodUsage) fetch() tea.Cmd {
+	m.fetches++
+	m.fetching = true
+	return tea.Batch(m.collect(), m.spinner.Tick)
+}
+
+// collect returns the command fetching the pods off the update loop, only the ones of the node if opened from the node view
+// with a snapshot of the inputs, so the keys can change them meanwhile
+func (m PodUsage) collect() tea.Cmd {
+	session, fetch, inputs, collector, node := m.session, m.fetches, *m.Args, m.Collector, m.Node
+	return func() tea.Msg {
+		stats, err := collector.PodsFor(&inputs, node)
+		return statsMsg{session: session, fetch: fetch, stats: stats, err: err}
+	}
+}
+
+// receive shows the fetched pods, or keeps the last good ones on error
+func (m *PodUsage) receive(msg statsMsg) {
+	m.fetching = false
+	if msg.err != nil {
+		// Keep the last good data and retry with backoff
+		utils.Logger.Debug("Refresh failed: ", msg.err)
+		m.err = msg.err
+		m.failures++
+	} else {
+		m.Podstats = msg.stats
+		m.updated = time.Now()
+		m.err = nil
+		m.failures = 0
+	}
+	m.render()
+	m.viewport.SetContent(m.content)
+	m.moveCursor(0) // keep the cursor on a row when the list shrinks
+}
+
+// interval is the delay between refreshes, the one of --interval or changed with + and -
+func (m PodUsage) interval() time.Duration {
+	if m.Args.Interval > 0 {
+		return m.Args.Interval
+	}
+	return refreshInterval
+}
+
+// switchMetric moves to the next metric and fetches it, the fetch of the previous metric is dropped
+// the sort is kept when the new metric has it, else the pods are sorted by name
+func (m *PodUsage) switchMetric() tea.Cmd {
+	m.Args.Metrics = utils.Next(metrics, m.Args.Metrics)
+	if !utils.Contains(sortKeys(m.Args.Metrics), m.Args.SortBy) {
+		m.Args.SortBy = "name"
+	}
+	return m.fetch()
 }
 
 // Init Bubble Tea podusage
 func (m PodUsage) Init() tea.Cmd {
-	return tea.Batch(tickCmd(), tea.EnterAltScreen)
+	return tea.Batch(m.collect(), m.spinner.Tick, tickCmd(utils.Backoff(m.interval(), m.failures), m.session), tea.EnterAltScreen)
+}
+
+// Searching tells if the search input has the keys
+func (m PodUsage) Searching() bool {
+	return m.searching
 }
 
-func tickCmd() tea.Cmd {
-	return tea.Tick(time.Second*5, func(t time.Time) tea.Msg {
-		return tickMsg(t)
+func tickCmd(delay time.Duration, session int) tea.Cmd {
+	return tea.Tick(delay, func(t time.Time) tea.Msg {
+		return tickMsg{session: session}
 	})
 }
 
@@ -111,10 +273,73 @@ func (m PodUsage) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			m.searching = true
 			m.searchInput.Focus()
 			return m, nil
+		case msg.Type == tea.KeyRunes && (msg.Runes[0] == 'O' || msg.Runes[0] == 'o') && !m.searching:
+			// Sort by the next column, no sort key is a sort by name
+			sortBy := m.Args.SortBy
+			if sortBy == "" {
+				sortBy = "name"
+			}
+			m.Args.SortBy = utils.Next(sortKeys(m.Args.Metrics), sortBy)
+			m.render()
+			return m, nil
+		case msg.Type == tea.KeyRunes && (msg.Runes[0] == 'A' || msg.Runes[0] == 'a') && !m.searching:
+			// Toggle ascending and descending
+			m.Args.ReverseFlag = !m.Args.ReverseFlag
+			m.render()
+			return m, nil
+		case msg.Type == tea.KeyRunes && (msg.Runes[0] == 'M' || msg.Runes[0] == 'm') && !m.searching:
+			// Show the next metric
+			return m, m.switchMetric()
+		case msg.Type == tea.KeyRunes && (msg.Runes[0] == 'N' || msg.Runes[0] == 'n') && !m.searching:
+			// Show the pods of the next namespace, then the ones of all of them again
+			m.namespace = utils.Next(namespaces(m.Podstats), m.namespace)
+			m.cursor = 0
+			m.render()
+			m.viewport.SetContent(m.content)
+			m.viewport.GotoTop()
+			return m, nil
+		case msg.Type == tea.KeyRunes && (msg.Runes[0] == 'P' || msg.Runes[0] == 'p') && !m.searching:
+			// Pause or resume the refreshes
+			m.paused = !m.paused
+			m.render()
+			return m, nil
+		case msg.Type == tea.KeyRunes && (msg.Runes[0] == 'R' || msg.Runes[0] == 'r') && !m.searching:
+			// Refresh right away, paused or not
+			return m, m.refresh()
+		case msg.Type == tea.KeyRunes && (msg.Runes[0] == '+' || msg.Runes[0] == '=') && !m.searching:
+			// Refresh less often, from the next tick on
+			m.Args.Interval = m.interval() + time.Second
+			m.render()
+			return m, nil
+		case msg.Type == tea.KeyRunes && msg.Runes[0] == '-' && !m.searching:
+			// Refresh more often, down to every second
+			if interval := m.interval(); interval > time.Second {
+				m.Args.Interval = interval - time.Second
+			}
+			m.render()
+			return m, nil
+		case msg.Type == tea.KeyRunes && (msg.Runes[0] == 'I' || msg.Runes[0] == 'i') && !m.searching:
+			// Toggle the detail pane of the selected pod
+			m.detail = !m.detail
+			if header, _, _ := m.visible(); m.detail {
+				m.scrollTo(len(header) + m.cursor)
+			}
+			return m, nil
+		}
+
+		// Row selection works while searching too
+		switch msg.String() {
+		case "up":
+			m.moveCursor(-1)
+			return m, nil
+		case "down":
+			m.moveCursor(1)
+			return m, nil
 		}
 
 		if m.searching {
 			m.searchInput, cmd = m.searchInput.Update(msg)
+			m.cursor = 0
 			cmds = append(cmds, cmd)
 			return m, tea.Batch(cmds...)
 		}
@@ -128,7 +353,7 @@ func (m PodUsage) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		case "right":
 			maxScroll := m.maxWidth - m.width
 			if maxScroll > 0 && m.xOffset < maxScroll {
-				m.xOffset = min(m.xOffset+5, maxScroll)
+				m.xOffset = common.Min(m.xOffset+5, maxScroll)
 			}
 		}
 	case tea.WindowSizeMsg:
@@ -140,22 +365,28 @@ func (m PodUsage) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.height = msg.Height
 		m.viewport.Width = msg.Width
 		m.viewport.Height = msg.Height - 1
-	case tickMsg:
-		m.ClusterInfo = k8s.ClusterInfo()
-		m.Podstats = k8s.Pods(m.Args)
-		var output strings.Builder
-		MetricsHandler(m, &output)
-		m.content = output.String()
-
-		m.maxWidth = 0
-		for _, line := range strings.Split(m.content, "\n") {
-			if len(line) > m.maxWidth {
-				m.maxWidth = len(line)
-			}
-		}
 
+		// Re-render content with new size
+		m.render()
 		m.viewport.SetContent(m.content)
-		cmds = append(cmds, tickCmd())
+	case tickMsg:
+		if msg.session != m.session {
+			break
+		}
+		if !m.paused {
+			cmds = append(cmds, m.refresh())
+		}
+		cmds = append(cmds, tickCmd(utils.Backoff(m.interval(), m.failures), m.session))
+	case statsMsg:
+		if msg.session == m.session && msg.fetch == m.fetches {
+			m.receive(msg)
+		}
+	case spinner.TickMsg:
+		// The spinner stops with the fetch
+		if m.fetching {
+			m.spinner, cmd = m.spinner.Update(msg)
+			cmds = append(cmds, cmd)
+		}
 	}
 
 	m.viewport, cmd = m.viewport.Update(msg)
@@ -163,86 +394,43 @@ func (m PodUsage) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	return m, tea.Batch(cmds...)
 }
 
-// Helper function to get minimum of two integers
-func min(a, b int) int {
-	if a < b {
-		return a
-	}
-	return b
-}
-
-func GetBar(decider float64) progress.Model {
-	decider = decider * 100
-
-	var prog progress.Model
-	// decide which color to use based on the usage percentage below 30% is green, above 70% is red, else yellow
-	if decider < 30 {
-		prog = progress.New(progress.WithScaledGradient("#0bad5d", "#74b03f"))
-	} else if decider > 70 {
-		prog = progress.New(progress.WithScaledGradient("#13B013", "#F11658"))
-	} else {
-		prog = progress.New(progress.WithScaledGradient("#13B013", "#F18016"))
-	}
-	return prog
-}
-
 // View renders bubble tea
 func (m PodUsage) View() string {
 	if !m.ready {
 		return "Initializing..."
 	}
 
-	lines := strings.Split(m.content, "\n")
-	var displayLines []string
-
-	searchTerm := strings.ToLower(m.searchInput.Value())
-
-	// Always include the header lines - till the table header
-	for i := 0; i < min(utils.HeaderLines, len(lines)); i++ {
-		if len(lines[i]) > m.xOffset {
-			displayLines = append(displayLines, lines[i][m.xOffset:])
-		} else {
-			displayLines = append(displayLines, "")
-		}
-	}
-
-	// For the rest of the content
-	for i := utils.HeaderLines; i < len(lines); i++ {
-		line := lines[i]
-		// If searching, only include lines that match the search term
-		if m.searching && searchTerm != "" {
-			if strings.Contains(strings.ToLower(line), searchTerm) {
-				if len(line) > m.xOffset {
-					displayLine := line[m.xOffset:]
-					// displayLine = highlightStyle.Render(displayLine)
-					displayLines = append(displayLines, displayLine)
-				} else {
-					displayLines = append(displayLines, "")
-				}
-			}
-		} else {
-			// If not searching, include all lines
-			if len(line) > m.xOffset {
-				displayLines = append(displayLines, line[m.xOffset:])
-			} else {
-				displayLines = append(displayLines, "")
-			}
-		}
-	}
-
-	viewportContent := strings.Join(displayLines, "\n")
-	m.viewport.SetContent(viewportContent)
+	header, lines, _ := m.visible()
+	m.viewport.SetContent(common.Table(header, lines, m.xOffset, m.cursor))
 
 	var helpText string
 	if m.searching {
-		matchCount := len(displayLines) - utils.HeaderLines // Subtract header lines
+		matchCount := len(lines)
 		helpText = fmt.Sprintf("\n%s %s (%d matches) (ESC to exit search)",
-			searchStyle.Render("Search:"),
+			common.SearchStyle.Render("Search:"),
 			m.searchInput.View(),
 			matchCount)
 	} else {
-		helpText = helpStyle("\nUse ← and → to scroll horizontally, S to search, Q or Ctrl+C to quit")
+		help := "\nUse ↑ and ↓ to select, I for its details, ← and → to scroll horizontally, S to search, O, A and M to change the sort, order and metric, N for the namespace, P to pause, R to refresh, + and - for the interval, Q or Ctrl+C to quit"
+		if m.Node != "" {
+			help = "\nUse ↑ and ↓ to select, I for its details, ← and → to scroll horizontally, S to search, O, A and M to change the sort, order and metric, N for the namespace, P to pause, R to refresh, + and - for the interval, Esc to go back to the nodes, Q or Ctrl+C to quit"
+		}
+		helpText = common.HelpStyle(help)
+	}
+	if m.fetching {
+		helpText = "\n" + m.spinner.View() + " Refreshing " + strings.TrimPrefix(helpText, "\n")
+	}
+
+	// The viewport gives up lines for the detail pane and the error banner
+	m.viewport.Height = m.bodyHeight()
+
+	var pane string
+	if detail := m.detailPane(); detail != "" {
+		pane = "\n" + detail
 	}
 
-	return fmt.Sprintf("%s%s", m.viewport.View(), helpText)
+	// Error banner for a failed refresh
+	banner := common.Banner(m.err, m.interval(), m.failures, m.width)
+
+	return fmt.Sprintf("%s%s%s%s", m.viewport.View(), pane, banner, helpText)
 }
@@ -2,12 +2,11 @@ package podmodel
 
 import (
 	"fmt"
-	"os"
-	"regexp"
 	"sort"
 	"strconv"
 	"strings"
 
+	"github.com/AKSarav/KubeNodeUsage/v3/cmd/common"
 	"github.com/AKSarav/KubeNodeUsage/v3/k8s"
 	"github.com/AKSarav/KubeNodeUsage/v3/utils"
 
@@ -23,6 +22,8 @@ func getUnit(metricType string) string {
 		unit = "Cores"
 	case "disk":
 		unit = "MB"
+	case "network":
+		unit = "KB/s"
 	}
 	return unit
 }
@@ -57,7 +58,7 @@ func RightMetric(m PodUsage, index int) float32 {
 			if m.Podstats[index].Limit_cpu > 0 {
 				return m.Podstats[index].Limit_cpu - m.Podstats[index].Usage_cpu
 			}
-			return float32(m.Podstats[index].Capacity_cpu/1000) - m.Podstats[index].Usage_cpu
+			return float32(m.Podstats[index].Capacity_cpu)/1000 - m.Podstats[index].Usage_cpu
 		} else if m.Args.SortBy == "capacity" || m.Args.SortBy == "max" {
 			return float32(m.Podstats[index].Capacity_cpu)
 		} else if m.Args.SortBy == "limit" {
@@ -67,6 +68,20 @@ func RightMetric(m PodUsage, index int) float32 {
 		} else if m.Args.SortBy == "color" || m.Args.SortBy == "usage" {
 			return m.Podstats[index].Usage_cpu_percent
 		}
+	case "disk":
+		if m.Args.SortBy == "capacity" || m.Args.SortBy == "max" {
+			return float32(m.Podstats[index].Node_disk_capacity)
+		} else if m.Args.SortBy == "color" || m.Args.SortBy == "usage" {
+			return float32(m.Podstats[index].Usage_disk)
+		}
+	case "network":
+		if m.Args.SortBy == "rx" {
+			return float32(m.Podstats[index].Rx_network)
+		} else if m.Args.SortBy == "tx" {
+			return float32(m.Podstats[index].Tx_network)
+		} else if m.Args.SortBy == "color" || m.Args.SortBy == "usage" {
+			return m.Podstats[index].Usage_network_percent
+		}
 	}
 	// default return
 	return m.Podstats[index].Usage_memory_percent
@@ -106,125 +121,61 @@ func SortByHandler(m PodUsage) {
 	}
 }
 
-func ApplyFilters(m PodUsage) []k8s.Pod {
-	if m.Args.FilterLabel != "" {
-		return FilterForLabel(m)
-	} else if m.Args.FilterNodes != "" {
-		return FilterForNode(m)
-	} else if m.Args.FilterColor != "" {
-		return FilterForColor(m)
-	} else {
-		return m.Podstats
+// metrics are the metrics the M key cycles through
+var metrics = []string{"memory", "cpu", "disk", "network"}
+
+// sortKeys returns the sort keys the O key cycles through for the metric
+func sortKeys(metric string) []string {
+	switch metric {
+	case "memory", "cpu":
+		return []string{"name", "namespace", "usage", "request", "limit", "free"}
+	case "disk":
+		return []string{"name", "namespace", "usage"}
+	case "network":
+		return []string{"name", "namespace", "usage", "rx", "tx"}
 	}
+	return []string{"name"}
 }
 
-func FilterForNode(m PodUsage) []k8s.Pod {
-	var filteredPods []k8s.Pod
-	FilterNodeInput := strings.Split(m.Args.FilterNodes, ",")
-
-	for _, pod := range m.Podstats {
-		for _, FilteredNode := range FilterNodeInput {
-			if matched, _ := regexp.MatchString(FilteredNode, pod.NodeName); matched {
-				filteredPods = append(filteredPods, pod)
-				break
-			}
-			// Also filter by pod name if that's what user wanted
-			if matched, _ := regexp.MatchString(FilteredNode, pod.Name); matched {
-				filteredPods = append(filteredPods, pod)
-				break
+// Rows returns the pods after applying the filters and the sort from the inputs
+// the pods of a node opened from the node view are the only ones fetched already
+func Rows(m PodUsage) []k8s.Pod {
+	if m.namespace != "" {
+		var inNamespace []k8s.Pod
+		for _, pod := range m.Podstats {
+			if pod.Namespace == m.namespace {
+				inNamespace = append(inNamespace, pod)
 			}
 		}
+		m.Podstats = inNamespace
 	}
-
-	if len(filteredPods) > 0 {
-		utils.Logger.Debug("Filter For Node results", filteredPods)
-		m.Podstats = filteredPods
-		return m.Podstats
-	} else {
-		utils.Logger.Errorf("No matching Pods found.. Exiting")
-		os.Exit(2)
-		return m.Podstats
-	}
+	m.Podstats = ApplyFilters(m)
+	SortByHandler(m)
+	return m.Podstats
 }
 
-func FilterForLabel(m PodUsage) []k8s.Pod {
-	var filteredPods []k8s.Pod
-
-	FilterKey := strings.Split(m.Args.FilterLabel, "=")[0]
-	FilterValue := strings.Split(m.Args.FilterLabel, "=")[1]
-
-	if FilterKey == "" || FilterValue == "" {
-		utils.Logger.Errorf("Filter Key or Value is empty.. Exiting")
-		os.Exit(2)
-	}
-
-	for _, pod := range m.Podstats {
-		if _, ok := pod.Labels[FilterKey]; ok {
-			if pod.Labels[FilterKey] == FilterValue {
-				filteredPods = append(filteredPods, pod)
-			}
+// namespaces returns the namespaces of the pods the N key cycles through, the pods of all of them first
+func namespaces(pods []k8s.Pod) []string {
+	seen := make(map[string]bool)
+	var list []string
+	for _, pod := range pods {
+		if !seen[pod.Namespace] {
+			seen[pod.Namespace] = true
+			list = append(list, pod.Namespace)
 		}
 	}
-
-	if len(filteredPods) > 0 {
-		utils.Logger.Debug("Filter For Label results", filteredPods)
-		m.Podstats = filteredPods
-		return m.Podstats
-	} else {
-		utils.Logger.Errorf("No matching Pods found.. Exiting")
-		os.Exit(2)
-		return m.Podstats
-	}
+	sort.Strings(list)
+	return append([]string{""}, list...)
 }
 
-func FilterForColor(m PodUsage) []k8s.Pod {
-	utils.Logger.Debug("Filter for Color called")
-	var filteredPods []k8s.Pod
-	var thresholdMin, thresholdMax float64
-
-	// Define the color threshold values
-	switch m.Args.FilterColor {
-	case "red":
-		thresholdMin = 70
-		thresholdMax = 100
-	case "orange":
-		thresholdMin = 30
-		thresholdMax = 70
-	case "green":
-		thresholdMin = 0
-		thresholdMax = 30
-	default:
-		thresholdMin = 0
-		thresholdMax = 100
-	}
-
-	// Filter pods based on metric and threshold values
-	for _, pod := range m.Podstats {
-		var usagepercent float64
-		switch m.Args.Metrics {
-		case "memory":
-			usagepercent = float64(pod.Usage_memory_percent) / 100.0
-		case "cpu":
-			usagepercent = float64(pod.Usage_cpu_percent) / 100.0
-		default:
-			if m.Args.Debug {
-				fmt.Println("No Matching Metric", m.Args.Metrics)
-			}
-		}
-
-		if (usagepercent*100) >= thresholdMin && (usagepercent*100) < thresholdMax {
-			filteredPods = append(filteredPods, pod)
-		}
-	}
-	if m.Args.Debug {
-		fmt.Println("Filter For Color result:", filteredPods)
-	}
-	return filteredPods
+// ApplyFilters keeps the pods matching all the filters of the inputs together
+func ApplyFilters(m PodUsage) []k8s.Pod {
+	return k8s.Filter(m.Podstats, m.Args)
 }
 
 func PrintDesign(output *strings.Builder, maxNameWidth int, maxNsWidth int, isMetricsDisk bool) {
 	if isMetricsDisk {
-		output.WriteString(strings.Repeat("-", maxNameWidth+maxNsWidth+55) + "\n")
+		output.WriteString(strings.Repeat("-", maxNameWidth+maxNsWidth+72) + "\n")
 	} else {
 		output.WriteString(strings.Repeat("-", maxNameWidth+maxNsWidth+60) + "\n")
 	}
@@ -239,12 +190,22 @@ func headlinePrinter(m *PodUsage, output *strings.Builder, Pods *[]k8s.Pod, maxN
 	// Adjust format based on metric type and label display
 	if m.Args.Metrics == "disk" {
 		if m.Args.LabelToDisplay != "" {
-			m.Format = "%-" + strconv.Itoa(*maxNameWidth) + "s %-" + strconv.Itoa(*maxNsWidth) + "s %-20s %-10s %-15s %-12s\n"
-			values := []interface{}{"Name", "Namespace", "Node", usageHeading, "Node Cap(GB)", m.Args.LabelAlias}
+			m.Format = "%-" + strconv.Itoa(*maxNameWidth) + "s %-" + strconv.Itoa(*maxNsWidth) + "s %-20s %-10s %-15s %-16s %-12s\n"
+			values := []interface{}{"Name", "Namespace", "Node", usageHeading, "Node Cap(GB)", "Source", m.Args.LabelAlias}
 			fmt.Fprintf(output, m.Format, values...)
 		} else {
-			m.Format = "%-" + strconv.Itoa(*maxNameWidth) + "s %-" + strconv.Itoa(*maxNsWidth) + "s %-20s %-10s %-15s\n"
-			values := []interface{}{"Name", "Namespace", "Node", usageHeading, "Node Cap(GB)"}
+			m.Format = "%-" + strconv.Itoa(*maxNameWidth) + "s %-" + strconv.Itoa(*maxNsWidth) + "s %-20s %-10s %-15s %s\n"
+			values := []interface{}{"Name", "Namespace", "Node", usageHeading, "Node Cap(GB)", "Source"}
+			fmt.Fprintf(output, m.Format, values...)
+		}
+	} else if m.Args.Metrics == "network" {
+		if m.Args.LabelToDisplay != "" {
+			m.Format = "%-" + strconv.Itoa(*maxNameWidth) + "s %-" + strconv.Itoa(*maxNsWidth) + "s %-20s %-10s %-10s %-12s %s\n"
+			values := []interface{}{"Name", "Namespace", "Node", "Rx(" + unit + ")", "Tx(" + unit + ")", m.Args.LabelAlias, "Usage%"}
+			fmt.Fprintf(output, m.Format, values...)
+		} else {
+			m.Format = "%-" + strconv.Itoa(*maxNameWidth) + "s %-" + strconv.Itoa(*maxNsWidth) + "s %-20s %-10s %-10s %s\n"
+			values := []interface{}{"Name", "Namespace", "Node", "Rx(" + unit + ")", "Tx(" + unit + ")", "Usage%"}
 			fmt.Fprintf(output, m.Format, values...)
 		}
 	} else {
@@ -261,12 +222,9 @@ func headlinePrinter(m *PodUsage, output *strings.Builder, Pods *[]k8s.Pod, maxN
 	}
 }
 
-func MetricsHandler(m PodUsage, output *strings.Builder) {
-	// Pods Filtering based on filters
-	filteredPods := ApplyFilters(m)
-
-	m.Podstats = filteredPods
-	SortByHandler(m)
+func MetricsHandler(m PodUsage, output *strings.Builder) []k8s.Pod {
+	// Pods Filtering and Sorting based on the inputs
+	filteredPods := Rows(m)
 
 	// decide formatting and Maximum width
 	maxNameWidth := 15
@@ -291,11 +249,19 @@ func MetricsHandler(m PodUsage, output *strings.Builder) {
 		fmt.Fprint(output, "\n# Context: ", m.ClusterInfo.Context, "\n# Version: ", m.ClusterInfo.Version, "\n# URL: ", m.ClusterInfo.URL, "\n\n")
 	}
 
-	fmt.Fprint(output, "# ", strcase.ToCamel(m.Args.Metrics), " Metrics for Pods\n\n")
+	fmt.Fprint(output, "# ", strcase.ToCamel(m.Args.Metrics), " Metrics for Pods")
+	if m.namespace != "" {
+		fmt.Fprint(output, " in ", m.namespace)
+	}
+	if m.Node != "" {
+		fmt.Fprint(output, " on ", m.Node)
+	}
+	fmt.Fprint(output, "\n")
+	fmt.Fprint(output, "# Sorted by: ", utils.SortIndicator(m.Args.SortBy, m.Args.ReverseFlag),
+		"\n# Last updated: ", utils.RefreshIndicator(m.updated, m.interval(), m.paused), "\n\n")
 
 	if m.Args.Metrics == "disk" {
 		fmt.Fprint(output, "# Usage % is not calculated as comparing the pod disk usage against node capacity would not make sense\n\n")
-		utils.HeaderLines = 16
 	}
 
 	headlinePrinter(&m, output, &filteredPods, &maxNameWidth, &maxNsWidth)
@@ -303,7 +269,7 @@ func MetricsHandler(m PodUsage, output *strings.Builder) {
 
 	if m.Args.Metrics == "memory" {
 		for _, pod := range filteredPods {
-			prog := GetBar(float64(pod.Usage_memory_percent) / 100.0)
+			prog := common.GetBar(float64(pod.Usage_memory_percent) / 100.0)
 
 			// Truncate node name if too long
 			nodeName := pod.NodeName
@@ -338,7 +304,7 @@ func MetricsHandler(m PodUsage, output *strings.Builder) {
 		}
 	} else if m.Args.Metrics == "cpu" {
 		for _, pod := range filteredPods {
-			prog := GetBar(float64(pod.Usage_cpu_percent) / 100.0)
+			prog := common.GetBar(float64(pod.Usage_cpu_percent) / 100.0)
 
 			// Truncate node name if too long
 			nodeName := pod.NodeName
@@ -371,6 +337,39 @@ func MetricsHandler(m PodUsage, output *strings.Builder) {
 				fmt.Fprintf(output, m.Format, values...)
 			}
 		}
+	} else if m.Args.Metrics == "network" {
+		for _, pod := range filteredPods {
+			prog := common.GetBar(float64(pod.Usage_network_percent) / 100.0)
+
+			// Truncate node name if too long
+			nodeName := pod.NodeName
+			if len(nodeName) > 20 {
+				nodeName = nodeName[:9] + "…"
+			}
+
+			if m.Args.LabelToDisplay != "" {
+				values := []interface{}{
+					pod.Name,
+					pod.Namespace,
+					nodeName,
+					fmt.Sprintf("%.1f", pod.Rx_network/1024),
+					fmt.Sprintf("%.1f", pod.Tx_network/1024),
+					pod.LabelToDisplay,
+					prog.ViewAs(float64(pod.Usage_network_percent) / 100.0),
+				}
+				fmt.Fprintf(output, m.Format, values...)
+			} else {
+				values := []interface{}{
+					pod.Name,
+					pod.Namespace,
+					nodeName,
+					fmt.Sprintf("%.1f", pod.Rx_network/1024),
+					fmt.Sprintf("%.1f", pod.Tx_network/1024),
+					prog.ViewAs(float64(pod.Usage_network_percent) / 100.0),
+				}
+				fmt.Fprintf(output, m.Format, values...)
+			}
+		}
 	} else if m.Args.Metrics == "disk" {
 		for _, pod := range filteredPods {
 			// Truncate node name if too long
@@ -379,13 +378,20 @@ func MetricsHandler(m PodUsage, output *strings.Builder) {
 				nodeName = nodeName[:9] + "…"
 			}
 
+			// No made up numbers when the kubelet has no usage for the pod
+			usage := fmt.Sprintf("%.2f", pod.Usage_disk)
+			if pod.Disk_source == k8s.SourceUnavailable {
+				usage = "NA"
+			}
+
 			if m.Args.LabelToDisplay != "" {
 				values := []interface{}{
 					pod.Name,
 					pod.Namespace,
 					nodeName,
-					fmt.Sprintf("%.2f", pod.Usage_disk),
+					usage,
 					fmt.Sprintf("%.1f", pod.Node_disk_capacity),
+					pod.Disk_source,
 					pod.LabelToDisplay,
 				}
 				fmt.Fprintf(output, m.Format, values...)
@@ -394,11 +400,71 @@ func MetricsHandler(m PodUsage, output *strings.Builder) {
 					pod.Name,
 					pod.Namespace,
 					nodeName,
-					fmt.Sprintf("%.2f", pod.Usage_disk),
+					usage,
 					fmt.Sprintf("%.1f", pod.Node_disk_capacity),
+					pod.Disk_source,
 				}
 				fmt.Fprintf(output, m.Format, values...)
 			}
 		}
 	}
+
+	return filteredPods
+}
+
+// detailLines describes the selected pod for the detail pane
+// every container with its usage against its own requests and limits, whatever the metric on screen
+func detailLines(pod k8s.Pod) []string {
+	owner := pod.Owner
+	if owner == "" {
+		owner = "none"
+	}
+
+	var lines []string
+	lines = append(lines, fmt.Sprintf("Pod: %s/%s   Node: %s   Status: %s   Owner: %s   Restarts: %d",
+		pod.Namespace, pod.Name, pod.NodeName, pod.Status, owner, pod.Restarts))
+
+	nameWidth := 12
+	for _, container := range pod.Containers {
+		if len(container.Name)+2 > nameWidth {
+			nameWidth = len(container.Name) + 2
+		}
+	}
+	format := "%-" + strconv.Itoa(nameWidth) + "s %-22s %-22s %-10s %s"
+	lines = append(lines, fmt.Sprintf(format, "Container", "CPU(Cores) use/req/lim", "Memory(MB) use/req/lim", "Restarts", "State"))
+	for _, container := range pod.Containers {
+		lines = append(lines, fmt.Sprintf(format,
+			container.Name,
+			fmt.Sprintf("%.2f/%.2f/%.2f", container.Usage_cpu, container.Request_cpu, container.Limit_cpu),
+			fmt.Sprintf("%d/%d/%d", container.Usage_memory, container.Request_memory, container.Limit_memory),
+			strconv.Itoa(container.Restarts),
+			container.State))
+	}
+
+	var conditions []string
+	for _, condition := range pod.Conditions {
+		if condition.Reason != "" {
+			conditions = append(conditions, condition.Type+"="+condition.Status+"("+condition.Reason+")")
+		} else {
+			conditions = append(conditions, condition.Type+"="+condition.Status)
+		}
+	}
+	lines = append(lines, fmt.Sprintf("%-12s %s", "Conditions", strings.Join(conditions, "  ")))
+	lines = append(lines, fmt.Sprintf("%-12s %s", "Labels", labelList(pod.Labels)))
+	return lines
+}
+
+// labelList returns the labels as key=value sorted by key
+func labelList(labels map[string]string) string {
+	keys := make([]string, 0, len(labels))
+	for key := range labels {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	pairs := make([]string, 0, len(keys))
+	for _, key := range keys {
+		pairs = append(pairs, key+"="+labels[key])
+	}
+	return strings.Join(pairs, "  ")
 }
@@ -0,0 +1,250 @@
+package volumemodel
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/AKSarav/KubeNodeUsage/v3/cmd/common"
+	"github.com/AKSarav/KubeNodeUsage/v3/k8s"
+	"github.com/AKSarav/KubeNodeUsage/v3/utils"
+
+	"github.com/charmbracelet/bubbles/spinner"
+	"github.com/charmbracelet/bubbles/textinput"
+	"github.com/charmbracelet/bubbles/viewport"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+type tickMsg time.Time
+
+// statsMsg carries the volumes fetched in the background
+type statsMsg struct {
+	stats []k8s.Volume
+	err   error
+}
+
+// refreshInterval is the delay between refreshes when the API calls succeed
+// the kubelet only recalculates the volume usage about once a minute
+const refreshInterval = time.Second * 10
+
+// volumeusage is the Bubble Tea model.
+type VolumeUsage struct {
+	ClusterInfo k8s.Cluster
+	Collector   *k8s.Collector
+	Volumestats []k8s.Volume
+	Args        *utils.Inputs
+	Format      string
+	viewport    viewport.Model
+	content     string
+	xOffset     int // Track horizontal scroll position
+	width       int // Terminal width
+	height      int // Terminal height
+	ready       bool
+	maxWidth    int // Maximum content width
+	searchInput textinput.Model
+	searching   bool
+	err         error // last refresh error, shown as a banner while the last good data stays on screen
+	failures    int   // consecutive refresh failures, used for the retry backoff
+	fetching    bool  // a fetch is running, the refreshes asked meanwhile are dropped
+	spinner     spinner.Model
+}
+
+// NewVolumeUsage creates a new VolumeUsage model
+func NewVolumeUsage(args *utils.Inputs, collector *k8s.Collector) VolumeUsage {
+	ti := textinput.New()
+	ti.Placeholder = "Search..."
+	ti.CharLimit = 156
+	ti.Width = 20
+
+	model := VolumeUsage{
+		Args:        args,
+		searchInput: ti,
+		Collector:   collector,
+		ClusterInfo: collector.ClusterInfo(),
+		content:     "",
+		xOffset:     0,
+		width:       0,
+		height:      0,
+		ready:       false,
+		maxWidth:    0,
+		searching:   false,
+		spinner:     spinner.New(spinner.WithSpinner(spinner.Dot)),
+	}
+
+	// The initial fetch is started by Init, the model starts empty
+	model.fetching = true
+
+	// Initialize content
+	var output strings.Builder
+	MetricsHandler(model, &output)
+	model.content = output.String()
+
+	// Calculate initial maxWidth
+	for _, line := range strings.Split(model.content, "\n") {
+		if len(line) > model.maxWidth {
+			model.maxWidth = len(line)
+		}
+	}
+
+	return model
+}
+
+// interval is the delay between refreshes, the one of --interval if given
+func (m VolumeUsage) interval() time.Duration {
+	if m.Args.Interval > 0 {
+		return m.Args.Interval
+	}
+	return refreshInterval
+}
+
+// collect returns the command fetching the volumes off the update loop
+func (m VolumeUsage) collect() tea.Cmd {
+	collector := m.Collector
+	return func() tea.Msg {
+		stats, err := collector.Volumes()
+		return statsMsg{stats: stats, err: err}
+	}
+}
+
+// Init Bubble Tea volumeusage
+func (m VolumeUsage) Init() tea.Cmd {
+	return tea.Batch(m.collect(), m.spinner.Tick, tickCmd(utils.Backoff(m.interval(), m.failures)), tea.EnterAltScreen)
+}
+
+func tickCmd(delay time.Duration) tea.Cmd {
+	return tea.Tick(delay, func(t time.Time) tea.Msg {
+		return tickMsg(t)
+	})
+}
+
+// Update method for Bubble Tea - for constant update loop
+func (m VolumeUsage) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	var (
+		cmd  tea.Cmd
+		cmds []tea.Cmd
+	)
+
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		switch {
+		case msg.Type == tea.KeyCtrlC:
+			return m, tea.Quit
+		case msg.Type == tea.KeyEsc && m.searching:
+			// Exit search mode
+			m.searching = false
+			m.searchInput.Reset()
+			m.searchInput.Blur()
+		case msg.Type == tea.KeyRunes && (msg.Runes[0] == 'Q' || msg.Runes[0] == 'q') && !m.searching:
+			return m, tea.Quit
+		case msg.Type == tea.KeyRunes && (msg.Runes[0] == 'S' || msg.Runes[0] == 's') && !m.searching:
+			// Enter search mode
+			m.searching = true
+			m.searchInput.Focus()
+			return m, nil
+		}
+
+		if m.searching {
+			m.searchInput, cmd = m.searchInput.Update(msg)
+			cmds = append(cmds, cmd)
+			return m, tea.Batch(cmds...)
+		}
+
+		// Handle horizontal scrolling only when not searching
+		switch msg.String() {
+		case "left":
+			if m.xOffset > 0 {
+				m.xOffset -= 5
+			}
+		case "right":
+			maxScroll := m.maxWidth - m.width
+			if maxScroll > 0 && m.xOffset < maxScroll {
+				m.xOffset = common.Min(m.xOffset+5, maxScroll)
+			}
+		}
+	case tea.WindowSizeMsg:
+		if !m.ready {
+			m.viewport = viewport.New(msg.Width, msg.Height-1)
+			m.ready = true
+		}
+		m.width = msg.Width
+		m.height = msg.Height
+		m.viewport.Width = msg.Width
+		m.viewport.Height = msg.Height - 1
+	case tickMsg:
+		// A refresh is dropped while the previous one is still running
+		if !m.fetching {
+			m.fetching = true
+			cmds = append(cmds, m.collect(), m.spinner.Tick)
+		}
+		cmds = append(cmds, tickCmd(utils.Backoff(m.interval(), m.failures)))
+	case spinner.TickMsg:
+		// The spinner stops with the fetch
+		if m.fetching {
+			m.spinner, cmd = m.spinner.Update(msg)
+			cmds = append(cmds, cmd)
+		}
+	case statsMsg:
+		m.fetching = false
+		if msg.err != nil {
+			// Keep the last good data and retry with backoff
+			utils.Logger.Debug("Refresh failed: ", msg.err)
+			m.err = msg.err
+			m.failures++
+		} else {
+			m.Volumestats = msg.stats
+			m.err = nil
+			m.failures = 0
+		}
+		var output strings.Builder
+		MetricsHandler(m, &output)
+		m.content = output.String()
+
+		m.maxWidth = 0
+		for _, line := range strings.Split(m.content, "\n") {
+			if len(line) > m.maxWidth {
+				m.maxWidth = len(line)
+			}
+		}
+
+		m.viewport.SetContent(m.content)
+	}
+
+	m.viewport, cmd = m.viewport.Update(msg)
+	cmds = append(cmds, cmd)
+	return m, tea.Batch(cmds...)
+}
+
+// View renders bubble tea
+func (m VolumeUsage) View() string {
+	if !m.ready {
+		return "Initializing..."
+	}
+
+	// The rows left after the search, there is no selection in the volume view
+	searchTerm := ""
+	if m.searching {
+		searchTerm = m.searchInput.Value()
+	}
+	header, lines, _ := common.Visible[k8s.Volume](m.content, nil, searchTerm)
+	m.viewport.SetContent(common.Table(header, lines, m.xOffset, -1))
+
+	var helpText string
+	if m.searching {
+		matchCount := len(lines)
+		helpText = fmt.Sprintf("\n%s %s (%d matches) (ESC to exit search)",
+			common.SearchStyle.Render("Search:"),
+			m.searchInput.View(),
+			matchCount)
+	} else {
+		helpText = common.HelpStyle("\nUse ← and → to scroll horizontally, S to search, Q or Ctrl+C to quit")
+	}
+	if m.fetching {
+		helpText = "\n" + m.spinner.View() + " Refreshing " + strings.TrimPrefix(helpText, "\n")
+	}
+
+	// Error banner for a failed refresh - the viewport gives up a line for it
+	m.viewport.Height = common.BodyHeight(m.height, m.err != nil, "")
+	banner := common.Banner(m.err, m.interval(), m.failures, m.width)
+
+	return fmt.Sprintf("%s%s%s", m.viewport.View(), banner, helpText)
+}
@@ -0,0 +1,151 @@
+package volumemodel
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/AKSarav/KubeNodeUsage/v3/cmd/common"
+	"github.com/AKSarav/KubeNodeUsage/v3/k8s"
+	"github.com/AKSarav/KubeNodeUsage/v3/utils"
+)
+
+// gbDivisor converts the volume bytes to GB
+const gbDivisor = 1024 * 1024 * 1024
+
+func DebugView(m VolumeUsage, output *strings.Builder) {
+	if m.Args.Debug {
+		fmt.Fprint(output, " \nDebug mode enabled")
+		fmt.Fprint(output, "\nArgs: ", m.Args)
+		fmt.Fprint(output, "\nVolumes: ", m.Volumestats)
+	}
+}
+
+func RightMetric(m VolumeUsage, index int) float32 {
+	switch m.Args.SortBy {
+	case "free":
+		return float32(m.Volumestats[index].Free_volume)
+	case "capacity", "max":
+		return float32(m.Volumestats[index].Capacity_volume)
+	}
+	// default return - usage and color
+	return m.Volumestats[index].Usage_volume_percent
+}
+
+func SortByHandler(m VolumeUsage) {
+	switch m.Args.SortBy {
+	case "", "name":
+		sort.Slice(m.Volumestats, func(i, j int) bool {
+			if m.Volumestats[i].Pod != m.Volumestats[j].Pod {
+				return (m.Volumestats[i].Pod < m.Volumestats[j].Pod) != m.Args.ReverseFlag
+			}
+			return (m.Volumestats[i].Name < m.Volumestats[j].Name) != m.Args.ReverseFlag
+		})
+	case "node":
+		sort.Slice(m.Volumestats, func(i, j int) bool {
+			return (m.Volumestats[i].NodeName < m.Volumestats[j].NodeName) != m.Args.ReverseFlag
+		})
+	default:
+		if !m.Args.ReverseFlag {
+			sort.Slice(m.Volumestats, func(i, j int) bool {
+				return RightMetric(m, i) < RightMetric(m, j)
+			})
+		} else {
+			sort.Slice(m.Volumestats, func(i, j int) bool {
+				return RightMetric(m, i) > RightMetric(m, j)
+			})
+		}
+	}
+}
+
+// Rows returns the volumes after applying the filters and the sort from the inputs
+func Rows(m VolumeUsage) []k8s.Volume {
+	m.Volumestats = ApplyFilters(m)
+	SortByHandler(m)
+	return m.Volumestats
+}
+
+// ApplyFilters keeps the volumes matching all the filters of the inputs together
+func ApplyFilters(m VolumeUsage) []k8s.Volume {
+	return k8s.Filter(m.Volumestats, m.Args)
+}
+
+func PrintDesign(output *strings.Builder, maxNameWidth int, maxPodWidth int, maxNsWidth int) {
+	output.WriteString(strings.Repeat("-", maxNameWidth+maxPodWidth+maxNsWidth+90) + "\n")
+}
+
+func headlinePrinter(m *VolumeUsage, output *strings.Builder, maxNameWidth int, maxPodWidth int, maxNsWidth int) {
+	widths := "%-" + strconv.Itoa(maxNameWidth) + "s %-" + strconv.Itoa(maxPodWidth) + "s %-" + strconv.Itoa(maxNsWidth) + "s %-20s %-10s %-25s %-10s %-10s"
+	if m.Args.LabelToDisplay != "" {
+		m.Format = widths + " %-12s %s\n"
+		fmt.Fprintf(output, m.Format, "Volume", "Pod", "Namespace", "Node", "Type", "PVC", "Used(GB)", "Cap(GB)", m.Args.LabelAlias, "Usage%")
+	} else {
+		m.Format = widths + " %s\n"
+		fmt.Fprintf(output, m.Format, "Volume", "Pod", "Namespace", "Node", "Type", "PVC", "Used(GB)", "Cap(GB)", "Usage%")
+	}
+}
+
+// truncate shortens the value to fit its column
+func truncate(value string, width int) string {
+	if len(value) > width {
+		return value[:width-1] + "…"
+	}
+	return value
+}
+
+func MetricsHandler(m VolumeUsage, output *strings.Builder) {
+	// Volumes Filtering and Sorting based on the inputs
+	filteredVolumes := Rows(m)
+
+	// decide formatting and Maximum width
+	maxNameWidth := 10
+	maxPodWidth := 15
+	maxNsWidth := 12
+	for _, volume := range filteredVolumes {
+		if maxNameWidth < len(volume.Name) {
+			maxNameWidth = len(volume.Name)
+		}
+		if maxPodWidth < len(volume.Pod) {
+			maxPodWidth = len(volume.Pod)
+		}
+		if maxNsWidth < len(volume.Namespace) {
+			maxNsWidth = len(volume.Namespace)
+		}
+	}
+
+	// Header and Version info
+	fmt.Fprintf(output, "\n# KubeNodeUsage - Volume View\n# Version: %s\n# https://github.com/AKSarav/KubeNodeUsage\n\n", utils.Version)
+
+	if !m.Args.NoInfo {
+		fmt.Fprint(output, "\n# Context: ", m.ClusterInfo.Context, "\n# Version: ", m.ClusterInfo.Version, "\n# URL: ", m.ClusterInfo.URL, "\n\n")
+	}
+
+	fmt.Fprint(output, "# Volume Usage for Pods\n\n")
+
+	headlinePrinter(&m, output, maxNameWidth, maxPodWidth, maxNsWidth)
+	PrintDesign(output, maxNameWidth, maxPodWidth, maxNsWidth)
+
+	for _, volume := range filteredVolumes {
+		prog := common.GetBar(float64(volume.Usage_volume_percent) / 100.0)
+		pvc := volume.PVC
+		if pvc == "" {
+			pvc = "-"
+		}
+		values := []interface{}{
+			volume.Name,
+			volume.Pod,
+			volume.Namespace,
+			truncate(volume.NodeName, 20),
+			volume.Type,
+			truncate(pvc, 25),
+			fmt.Sprintf("%.2f", float64(volume.Usage_volume)/gbDivisor),
+			fmt.Sprintf("%.2f", float64(volume.Capacity_volume)/gbDivisor),
+		}
+		if m.Args.LabelToDisplay != "" {
+			values = append(values, volume.LabelToDisplay)
+		}
+		values = append(values, prog.ViewAs(float64(volume.Usage_volume_percent)/100.0))
+		fmt.Fprintf(output, m.Format, values...)
+	}
+}
@@ -0,0 +1,74 @@
+package k8s
+
+import (
+	"fmt"
+
+	"github.com/AKSarav/KubeNodeUsage/v3/utils"
+
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	metricsv "k8s.io/metrics/pkg/client/clientset/versioned"
+)
+
+// Clients holds the kubernetes and metrics clientsets built from the resolved configuration
+type Clients struct {
+	Config    *rest.Config
+	Clientset *kubernetes.Clientset
+	Metrics   *metricsv.Clientset
+	Context   string
+}
+
+// clientConfig returns the kubeconfig loader with the standard clientcmd loading rules
+// KUBECONFIG list merging, --kubeconfig and --context overrides
+// and in-cluster config as the last resort when running inside a pod
+func clientConfig(inputs *utils.Inputs) clientcmd.ClientConfig {
+	rules := clientcmd.NewDefaultClientConfigLoadingRules()
+	if inputs.Kubeconfig != "" {
+		rules.ExplicitPath = inputs.Kubeconfig
+	}
+
+	overrides := &clientcmd.ConfigOverrides{}
+	overrides.CurrentContext = inputs.Context
+
+	return clientcmd.NewNonInteractiveDeferredLoadingClientConfig(rules, overrides)
+}
+
+// NewClients is the shared client factory used by both node and pod views
+func NewClients(inputs *utils.Inputs) (*Clients, error) {
+	cc := clientConfig(inputs)
+
+	config, err := cc.ClientConfig()
+	if err != nil {
+		return nil, fmt.Errorf("not able to load the kubernetes configuration: %v", err)
+	}
+
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return nil, fmt.Errorf("not able to create the kubernetes client: %v", err)
+	}
+
+	mc, err := metricsv.NewForConfig(config)
+	if err != nil {
+		return nil, fmt.Errorf("not able to create the metrics client: %v", err)
+	}
+
+	clients := &Clients{
+		Config:    config,
+		Clientset: clientset,
+		Metrics:   mc,
+		Context:   inputs.Context,
+	}
+
+	// Resolve the context name when not given explicitly
+	// RawConfig is empty when running with in-cluster config
+	if clients.Context == "" {
+		if raw, err := cc.RawConfig(); err == nil && raw.CurrentContext != "" {
+			clients.Context = raw.CurrentContext
+		} else {
+			clients.Context = "in-cluster"
+		}
+	}
+
+	return clients, nil
+}
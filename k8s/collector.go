@@ -0,0 +1,73 @@
+package k8s
+
+import (
+	"sync"
+
+	"github.com/AKSarav/KubeNodeUsage/v3/utils"
+)
+
+// Collector owns the kubernetes clients and the cluster info for the whole session
+// It is created once at startup and shared with the models, so every refresh
+// reuses the same clientsets instead of reloading the kubeconfig
+type Collector struct {
+	Inputs  *utils.Inputs
+	clients *Clients
+	cluster Cluster
+	cache   *informerCache // nil when listing from the API on every refresh
+
+	// last good kubelet summary of every node, used when a kubelet stops answering
+	summaryMu     sync.Mutex
+	lastSummaries map[string]*KubeletStats
+
+	// last network samples of the nodes and the pods, the rates are the difference between two samples
+	networkMu   sync.Mutex
+	nodeSamples map[string]networkSample
+	podSamples  map[string]networkSample
+}
+
+// NewCollector builds the clients and fetches the cluster info once
+func NewCollector(inputs *utils.Inputs) (*Collector, error) {
+	utils.InitLogger()
+
+	clients, err := NewClients(inputs)
+	if err != nil {
+		return nil, err
+	}
+
+	cluster, err := clusterInfo(clients)
+	if err != nil {
+		return nil, err
+	}
+
+	collector := &Collector{
+		Inputs:  inputs,
+		clients: clients,
+		cluster: cluster,
+	}
+
+	// Watch nodes and pods with shared informers if requested
+	// fall back to listing them on every refresh when watch is not permitted
+	if inputs.Watch {
+		cache, err := startInformers(clients)
+		if err != nil {
+			utils.Logger.Warn("Unable to watch nodes and pods, falling back to list on every refresh: ", err)
+		} else {
+			collector.cache = cache
+		}
+	}
+
+	return collector, nil
+}
+
+// Close stops the informers if they are running
+func (c *Collector) Close() {
+	if c.cache != nil {
+		close(c.cache.stop)
+		c.cache = nil
+	}
+}
+
+// ClusterInfo returns the cluster info cached at startup
+func (c *Collector) ClusterInfo() Cluster {
+	return c.cluster
+}
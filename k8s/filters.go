@@ -0,0 +1,226 @@
+package k8s
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/AKSarav/KubeNodeUsage/v3/utils"
+
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// Filterable is a row the filters of the inputs apply to - a node, a pod or a volume
+type Filterable interface {
+	// FilterNames returns the names --filternodes is matched against
+	FilterNames() []string
+	// FilterLabels returns the labels the --filterlabel selector is matched against
+	FilterLabels() map[string]string
+	// UsagePercent returns the usage --filtercolor is matched against for the metric on screen
+	UsagePercent(metric string) float32
+	// Available tells if the row has a usage for the metric, the rows without one match no color
+	Available(metric string) bool
+}
+
+// Filter keeps the rows matching every filter of the inputs
+// --filternodes, --filterlabel and --filtercolor apply together, the ones not given match everything
+// the label selector is pushed down to the API as well, it is applied again for the rows listed without it
+func Filter[T Filterable](rows []T, inputs *utils.Inputs) []T {
+	patterns := namePatterns(inputs.FilterNodes)
+	selector, err := LabelSelector(inputs.FilterLabel)
+	if err != nil {
+		// Checked at startup, nothing matches an invalid selector
+		utils.Logger.Debug("Invalid label selector ", inputs.FilterLabel, ": ", err)
+		return nil
+	}
+
+	var filtered []T
+	for _, row := range rows {
+		if matchNames(row, patterns) && selector.Matches(labels.Set(row.FilterLabels())) && matchColor(row, inputs.FilterColor, inputs.Metrics) {
+			filtered = append(filtered, row)
+		}
+	}
+	utils.Logger.Debug("Filter results ", filtered)
+	return filtered
+}
+
+// namePatterns compiles the comma separated regular expressions of --filternodes
+// the patterns are checked at startup, an invalid one here never matches
+func namePatterns(filter string) []*regexp.Regexp {
+	if filter == "" {
+		return nil
+	}
+	var patterns []*regexp.Regexp
+	for _, pattern := range strings.Split(filter, ",") {
+		if re, err := regexp.Compile(pattern); err == nil {
+			patterns = append(patterns, re)
+		} else {
+			utils.Logger.Debug("Invalid filter pattern ", pattern, ": ", err)
+		}
+	}
+	return patterns
+}
+
+// matchNames tells if any of the names of the row matches any of the patterns
+func matchNames(row Filterable, patterns []*regexp.Regexp) bool {
+	if patterns == nil {
+		return true
+	}
+	for _, name := range row.FilterNames() {
+		if name == "" {
+			continue
+		}
+		for _, re := range patterns {
+			if re.MatchString(name) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// LabelSelector parses --filterlabel with the kubernetes label selector syntax
+// like pool=gpu, tier!=web, env in (prod,staging), !spot or a comma separated list of them
+// no filter selects everything
+func LabelSelector(filter string) (labels.Selector, error) {
+	if filter == "" {
+		return labels.Everything(), nil
+	}
+	selector, err := labels.Parse(filter)
+	if err != nil {
+		return nil, fmt.Errorf("invalid label selector %q: %v", filter, err)
+	}
+	return selector, nil
+}
+
+// matchColor tells if the usage of the row is in the range of the color
+// green is below 30%, orange below 70% and red anything above, overcommitted rows included
+func matchColor(row Filterable, color string, metric string) bool {
+	if color != "" && !row.Available(metric) {
+		return false
+	}
+	usage := row.UsagePercent(metric)
+	switch color {
+	case "red":
+		return usage >= 70
+	case "orange":
+		return usage >= 30 && usage < 70
+	case "green":
+		return usage < 30
+	}
+	return true
+}
+
+// FilterNames of a node is its name
+func (n Node) FilterNames() []string {
+	return []string{n.Name}
+}
+
+// FilterLabels of a node are its own labels
+func (n Node) FilterLabels() map[string]string {
+	return n.Labels
+}
+
+// UsagePercent of a node for the metric, the most used resource in the combined view
+func (n Node) UsagePercent(metric string) float32 {
+	switch metric {
+	case "memory":
+		return n.Usage_memory_percent
+	case "cpu":
+		return n.Usage_cpu_percent
+	case "disk":
+		return n.Usage_disk_percent
+	case "network":
+		return n.Usage_network_percent
+	case "all":
+		usage := n.Usage_memory_percent
+		if n.Usage_cpu_percent > usage {
+			usage = n.Usage_cpu_percent
+		}
+		if n.Usage_disk_percent > usage {
+			usage = n.Usage_disk_percent
+		}
+		return usage
+	}
+	return 0
+}
+
+// Available tells if the node has a usage for the metric, the disk usage can be unknown
+// the combined view has the memory and cpu of every node
+func (n Node) Available(metric string) bool {
+	return metric != "disk" || n.Disk_source != SourceUnavailable
+}
+
+// FilterNames of a pod are its node and its own name
+func (p Pod) FilterNames() []string {
+	return []string{p.NodeName, p.Name}
+}
+
+// FilterLabels of a pod are its own labels
+func (p Pod) FilterLabels() map[string]string {
+	return p.Labels
+}
+
+// UsagePercent of a pod for the metric, the disk usage of pods has no percent
+func (p Pod) UsagePercent(metric string) float32 {
+	switch metric {
+	case "memory":
+		return p.Usage_memory_percent
+	case "cpu":
+		return p.Usage_cpu_percent
+	case "network":
+		return p.Usage_network_percent
+	}
+	return 0
+}
+
+// Available tells if the pod has a usage for the metric, the disk usage can be unknown
+func (p Pod) Available(metric string) bool {
+	return metric != "disk" || p.Disk_source != SourceUnavailable
+}
+
+// FilterNames of a volume are its node, its pod and its claim
+func (v Volume) FilterNames() []string {
+	return []string{v.NodeName, v.Pod, v.PVC}
+}
+
+// FilterLabels of a volume are the labels of its pod
+func (v Volume) FilterLabels() map[string]string {
+	return v.Labels
+}
+
+// UsagePercent of a volume is the one of its filesystem whatever the metric
+func (v Volume) UsagePercent(metric string) float32 {
+	return v.Usage_volume_percent
+}
+
+// Available is always true for a volume, it is listed from its filesystem stats
+func (v Volume) Available(metric string) bool {
+	return true
+}
+
+// FilterNames of a namespace is its name, the pods are filtered before they are summed up
+func (n Namespace) FilterNames() []string {
+	return []string{n.Name}
+}
+
+// FilterLabels of a namespace are none, the label selector is for the pods summed up
+func (n Namespace) FilterLabels() map[string]string {
+	return nil
+}
+
+// UsagePercent of a namespace is its share of the cluster for the metric
+func (n Namespace) UsagePercent(metric string) float32 {
+	switch metric {
+	case "memory":
+		return n.Usage_memory_percent
+	case "cpu":
+		return n.Usage_cpu_percent
+	}
+	return 0
+}
+
+// Available is always true for a namespace, the usage of its pods is summed up
+func (n Namespace) Available(metric string) bool {
+	return true
+}
@@ -0,0 +1,148 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	authv1 "k8s.io/api/authorization/v1"
+	core "k8s.io/api/core/v1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/informers"
+	corelisters "k8s.io/client-go/listers/core/v1"
+)
+
+// cacheSyncTimeout is how long we wait for the initial list of nodes and pods
+const cacheSyncTimeout = 60 * time.Second
+
+// informerCache keeps nodes and pods in a local cache fed by shared informers
+// so a refresh only has to fetch the metrics
+type informerCache struct {
+	nodes corelisters.NodeLister
+	pods  corelisters.PodLister
+	stop  chan struct{}
+}
+
+// canWatch checks with a SelfSubjectAccessReview if we are allowed to list and watch the resource
+func canWatch(clients *Clients, resource string) error {
+	for _, verb := range []string{"list", "watch"} {
+		review := &authv1.SelfSubjectAccessReview{
+			Spec: authv1.SelfSubjectAccessReviewSpec{
+				ResourceAttributes: &authv1.ResourceAttributes{
+					Verb:     verb,
+					Resource: resource,
+				},
+			},
+		}
+
+		result, err := clients.Clientset.AuthorizationV1().SelfSubjectAccessReviews().Create(context.TODO(), review, v1.CreateOptions{})
+		if err != nil {
+			return fmt.Errorf("unable to verify %s permission on %s: %v", verb, resource, err)
+		}
+		if !result.Status.Allowed {
+			return fmt.Errorf("not allowed to %s %s", verb, resource)
+		}
+	}
+	return nil
+}
+
+// startInformers starts the node and pod informers and waits for the caches to sync
+func startInformers(clients *Clients) (*informerCache, error) {
+	for _, resource := range []string{"nodes", "pods"} {
+		if err := canWatch(clients, resource); err != nil {
+			return nil, err
+		}
+	}
+
+	factory := informers.NewSharedInformerFactory(clients.Clientset, 0)
+	nodeInformer := factory.Core().V1().Nodes()
+	podInformer := factory.Core().V1().Pods()
+
+	// Managed fields are never used and are a big part of every object in memory
+	stripManagedFields := func(obj interface{}) (interface{}, error) {
+		if accessor, ok := obj.(v1.ObjectMetaAccessor); ok {
+			accessor.GetObjectMeta().SetManagedFields(nil)
+		}
+		return obj, nil
+	}
+	nodeInformer.Informer().SetTransform(stripManagedFields)
+	podInformer.Informer().SetTransform(stripManagedFields)
+
+	cache := &informerCache{
+		nodes: nodeInformer.Lister(),
+		pods:  podInformer.Lister(),
+		stop:  make(chan struct{}),
+	}
+
+	factory.Start(cache.stop)
+
+	ctx, cancel := context.WithTimeout(context.Background(), cacheSyncTimeout)
+	defer cancel()
+	for informerType, synced := range factory.WaitForCacheSync(ctx.Done()) {
+		if !synced {
+			close(cache.stop)
+			return nil, fmt.Errorf("timed out waiting for %v cache to sync", informerType)
+		}
+	}
+
+	return cache, nil
+}
+
+// listNodes returns the nodes matching the selector from the informer cache when watching, otherwise from the API
+func (c *Collector) listNodes(selector labels.Selector) ([]*core.Node, error) {
+	if c.cache != nil {
+		return c.cache.nodes.List(selector)
+	}
+
+	nodes, err := c.clients.Clientset.CoreV1().Nodes().List(context.TODO(), v1.ListOptions{LabelSelector: selector.String()})
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*core.Node, 0, len(nodes.Items))
+	for i := range nodes.Items {
+		result = append(result, &nodes.Items[i])
+	}
+	return result, nil
+}
+
+// listPods returns the pods of the namespaces matching the selector from the informer cache when watching,
+// otherwise from the API with one call per namespace, no namespaces lists the pods of all of them
+// a node keeps the pods scheduled on it only, with a field selector so the API server filters them
+func (c *Collector) listPods(namespaces []string, node string, selector labels.Selector) ([]*core.Pod, error) {
+	if namespaces == nil {
+		namespaces = []string{""}
+	}
+
+	options := v1.ListOptions{LabelSelector: selector.String()}
+	if node != "" {
+		options.FieldSelector = fields.OneTermEqualSelector("spec.nodeName", node).String()
+	}
+
+	var result []*core.Pod
+	for _, namespace := range namespaces {
+		if c.cache != nil {
+			pods, err := c.cache.pods.Pods(namespace).List(selector)
+			if err != nil {
+				return nil, err
+			}
+			for _, pod := range pods {
+				if node == "" || pod.Spec.NodeName == node {
+					result = append(result, pod)
+				}
+			}
+			continue
+		}
+
+		pods, err := c.clients.Clientset.CoreV1().Pods(namespace).List(context.TODO(), options)
+		if err != nil {
+			return nil, err
+		}
+		for i := range pods.Items {
+			result = append(result, &pods.Items[i])
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,107 @@
+package k8s
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	core "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// kubeletWorkers bounds how many /stats/summary requests are in flight at the same time
+const kubeletWorkers = 8
+
+// kubeletTimeout is how long we wait for a single kubelet before giving up on it for this refresh
+const kubeletTimeout = 5 * time.Second
+
+// kubeletSummary is the /stats/summary of a node or the error we got fetching it
+// stale is set when the fetch failed and stats are the last ones we got from the kubelet
+type kubeletSummary struct {
+	stats *KubeletStats
+	err   error
+	stale bool
+}
+
+// state is shown next to the disk usage, empty when the summary is fresh
+func (s kubeletSummary) state() string {
+	switch {
+	case s.err == nil:
+		return ""
+	case s.stale:
+		return "stale"
+	default:
+		return "unreachable"
+	}
+}
+
+// fetchSummaries fetches the /stats/summary of every node once
+// the requests run concurrently across nodes with a bounded pool of workers
+// and every node gets its own timeout so one unreachable kubelet does not hold up the rest
+func fetchSummaries(clientset *kubernetes.Clientset, nodes []*core.Node) map[string]kubeletSummary {
+	summaries := make(map[string]kubeletSummary, len(nodes))
+	queue := make(chan *core.Node)
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for i := 0; i < kubeletWorkers && i < len(nodes); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for node := range queue {
+				ctx, cancel := context.WithTimeout(context.Background(), kubeletTimeout)
+				stats, err := getKubeletStats(ctx, clientset, node)
+				cancel()
+
+				mu.Lock()
+				summaries[node.Name] = kubeletSummary{stats: stats, err: err}
+				mu.Unlock()
+			}
+		}()
+	}
+
+	for _, node := range nodes {
+		queue <- node
+	}
+	close(queue)
+	wg.Wait()
+
+	return summaries
+}
+
+// kubeletSummaries fetches the summaries of the nodes and keeps the last good one of every node
+// a node whose kubelet fails this time falls back to its last summary marked as stale
+// complete is set when nodes are all the nodes of the cluster, the summaries of the nodes
+// not listed anymore, deleted or scaled in, are only dropped then
+func (c *Collector) kubeletSummaries(nodes []*core.Node, complete bool) map[string]kubeletSummary {
+	return c.mergeSummaries(fetchSummaries(c.clients.Clientset, nodes), complete)
+}
+
+// mergeSummaries records the good summaries and falls back to the last good one for the failed ones
+// a partial fetch, the hosts of some pods or the nodes of a selector, leaves the other nodes alone
+func (c *Collector) mergeSummaries(summaries map[string]kubeletSummary, complete bool) map[string]kubeletSummary {
+	c.summaryMu.Lock()
+	defer c.summaryMu.Unlock()
+	if c.lastSummaries == nil {
+		c.lastSummaries = make(map[string]*KubeletStats)
+	}
+
+	if complete {
+		for name := range c.lastSummaries {
+			if _, listed := summaries[name]; !listed {
+				delete(c.lastSummaries, name)
+			}
+		}
+	}
+
+	for name, summary := range summaries {
+		if summary.err == nil {
+			c.lastSummaries[name] = summary.stats
+			continue
+		}
+		if last, ok := c.lastSummaries[name]; ok {
+			summaries[name] = kubeletSummary{stats: last, err: summary.err, stale: true}
+		}
+	}
+	return summaries
+}
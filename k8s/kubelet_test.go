@@ -0,0 +1,29 @@
+package k8s
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestMergeSummariesPartial(t *testing.T) {
+	a, b := &KubeletStats{}, &KubeletStats{}
+	c := &Collector{lastSummaries: map[string]*KubeletStats{"a": a, "b": b}}
+
+	// The hosts of some pods only, the kubelet of a fails
+	summaries := c.mergeSummaries(map[string]kubeletSummary{"a": {err: errors.New("timeout")}}, false)
+	if summaries["a"].stats != a || !summaries["a"].stale {
+		t.Errorf("failed node a: got %+v, want its last summary marked as stale", summaries["a"])
+	}
+	if c.lastSummaries["b"] != b {
+		t.Errorf("node b left out of a partial fetch lost its last summary")
+	}
+
+	// All the nodes of the cluster, b is not there anymore
+	c.mergeSummaries(map[string]kubeletSummary{"a": {stats: a}}, true)
+	if _, ok := c.lastSummaries["b"]; ok {
+		t.Errorf("node b missing from a complete fetch kept its last summary")
+	}
+	if c.lastSummaries["a"] != a {
+		t.Errorf("node a lost its summary")
+	}
+}
@@ -0,0 +1,184 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/AKSarav/KubeNodeUsage/v3/utils"
+
+	core "k8s.io/api/core/v1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/metrics/pkg/apis/metrics/v1beta1"
+)
+
+// PodNamespaces returns the namespaces of --namespace the pods are listed from
+// nil lists the pods of all namespaces, the default and what --all-namespaces asks for
+func PodNamespaces(inputs *utils.Inputs) []string {
+	if inputs.AllNamespaces {
+		return nil
+	}
+	return splitNamespaces(inputs.Namespace)
+}
+
+// splitNamespaces splits a comma separated list of namespaces, blanks and repeats left out
+// a namespace listed twice would fetch its pods twice
+func splitNamespaces(list string) []string {
+	var namespaces []string
+	for _, namespace := range strings.Split(list, ",") {
+		if namespace = strings.TrimSpace(namespace); namespace != "" && !utils.Contains(namespaces, namespace) {
+			namespaces = append(namespaces, namespace)
+		}
+	}
+	return namespaces
+}
+
+// scopedPods lists the pods matching the selector in the namespaces of the inputs, on the node if one is given
+// leaving out the ones of --exclude-namespace
+func (c *Collector) scopedPods(inputs *utils.Inputs, node string, selector labels.Selector) ([]*core.Pod, error) {
+	pods, err := c.listPods(PodNamespaces(inputs), node, selector)
+	if err != nil {
+		return nil, err
+	}
+
+	excluded := splitNamespaces(inputs.ExcludeNamespaces)
+	if len(excluded) == 0 {
+		return pods, nil
+	}
+
+	var kept []*core.Pod
+	for _, pod := range pods {
+		if !utils.Contains(excluded, pod.Namespace) {
+			kept = append(kept, pod)
+		}
+	}
+	return kept, nil
+}
+
+// scopedPodMetrics lists the pod metrics matching the selector in the namespaces of the inputs
+// the excluded namespaces are left to the pods they are matched with
+func (c *Collector) scopedPodMetrics(inputs *utils.Inputs, selector labels.Selector) ([]v1beta1.PodMetrics, error) {
+	namespaces := PodNamespaces(inputs)
+	if namespaces == nil {
+		namespaces = []string{""}
+	}
+
+	var podMetrics []v1beta1.PodMetrics
+	for _, namespace := range namespaces {
+		list, err := c.clients.Metrics.MetricsV1beta1().PodMetricses(namespace).List(context.TODO(), v1.ListOptions{LabelSelector: selector.String()})
+		if err != nil {
+			return nil, err
+		}
+		podMetrics = append(podMetrics, list.Items...)
+	}
+	return podMetrics, nil
+}
+
+// Namespace holds the usage, requests and limits of the pods of a namespace summed up
+// the percents are of the whole cluster, the json names carry the unit of every value
+type Namespace struct {
+	Name                 string  `json:"name"`
+	Pods                 int     `json:"pods"`
+	Usage_memory         int     `json:"usage_memory_mib"`
+	Usage_cpu            float32 `json:"usage_cpu_cores"`
+	Request_memory       int     `json:"request_memory_mib"`
+	Request_cpu          float32 `json:"request_cpu_cores"`
+	Limit_memory         int     `json:"limit_memory_mib"`
+	Limit_cpu            float32 `json:"limit_cpu_cores"`
+	Capacity_memory      int     `json:"capacity_memory_mib"` // of the cluster
+	Capacity_cpu         float32 `json:"capacity_cpu_cores"`  // of the cluster
+	Usage_memory_percent float32 `json:"usage_memory_percent"`
+	Usage_cpu_percent    float32 `json:"usage_cpu_percent"`
+}
+
+// ClusterCapacity holds the memory and cpu of the nodes, the allocatable ones with --basis allocatable
+// in bytes and millicores, converted once the namespaces are summed up against it
+type ClusterCapacity struct {
+	Memory int64 `json:"memory_bytes"`
+	Cpu    int64 `json:"cpu_millicores"`
+}
+
+// ClusterCapacityFor sums up the memory and cpu of the nodes against the basis of the inputs
+// every node without --filternodes and --filterlabel, otherwise the nodes the pods were restricted to:
+// the ones whose name matches --filternodes and the ones running the pods left by the filters
+func (c *Collector) ClusterCapacityFor(inputs *utils.Inputs, pods []Pod) (ClusterCapacity, error) {
+	nodes, err := c.listNodes(labels.Everything())
+	if err != nil {
+		return ClusterCapacity{}, fmt.Errorf("failed to get nodes: %v", err)
+	}
+
+	filtered := inputs.FilterNodes != "" || inputs.FilterLabel != ""
+	patterns := namePatterns(inputs.FilterNodes)
+	hosts := make(map[string]bool)
+	if filtered {
+		podInputs := *inputs
+		podInputs.FilterColor = ""
+		for _, pod := range Filter(pods, &podInputs) {
+			hosts[pod.NodeName] = true
+		}
+	}
+
+	var memory, cpu int64
+	for _, node := range nodes {
+		if filtered && !hosts[node.Name] && (patterns == nil || !matchNames(Node{Name: node.Name}, patterns)) {
+			continue
+		}
+		nodeMemory := node.Status.Capacity.Memory().Value()
+		nodeCpu := node.Status.Capacity.Cpu().MilliValue()
+		if inputs.Basis == "allocatable" && !node.Status.Allocatable.Memory().IsZero() {
+			nodeMemory = node.Status.Allocatable.Memory().Value()
+		}
+		if inputs.Basis == "allocatable" && !node.Status.Allocatable.Cpu().IsZero() {
+			nodeCpu = node.Status.Allocatable.Cpu().MilliValue()
+		}
+		memory += nodeMemory
+		cpu += nodeCpu
+	}
+
+	return ClusterCapacity{Memory: memory, Cpu: cpu}, nil
+}
+
+// ByNamespace sums up the pods per namespace, from their containers so both metrics are there
+// whatever the metric the pods were collected for
+// the bytes and millicores are summed up and converted to MB and cores once per namespace
+func ByNamespace(pods []Pod, capacity ClusterCapacity) []Namespace {
+	index := make(map[string]int)
+	var namespaces []Namespace
+	var sums []rawAmounts
+	for _, pod := range pods {
+		i, ok := index[pod.Namespace]
+		if !ok {
+			i = len(namespaces)
+			index[pod.Namespace] = i
+			namespaces = append(namespaces, Namespace{Name: pod.Namespace})
+			sums = append(sums, rawAmounts{})
+		}
+
+		namespaces[i].Pods++
+		sum := &sums[i]
+		for _, container := range pod.Containers {
+			sum.usageMemory += container.raw.usageMemory
+			sum.requestMemory += container.raw.requestMemory
+			sum.limitMemory += container.raw.limitMemory
+			sum.usageCpu += container.raw.usageCpu
+			sum.requestCpu += container.raw.requestCpu
+			sum.limitCpu += container.raw.limitCpu
+		}
+	}
+
+	for i, sum := range sums {
+		namespace := &namespaces[i]
+		namespace.Usage_memory = int(sum.usageMemory / (1024 * 1024))
+		namespace.Request_memory = int(sum.requestMemory / (1024 * 1024))
+		namespace.Limit_memory = int(sum.limitMemory / (1024 * 1024))
+		namespace.Capacity_memory = int(capacity.Memory / (1024 * 1024))
+		namespace.Usage_cpu = float32(sum.usageCpu) / 1000
+		namespace.Request_cpu = float32(sum.requestCpu) / 1000
+		namespace.Limit_cpu = float32(sum.limitCpu) / 1000
+		namespace.Capacity_cpu = float32(capacity.Cpu) / 1000
+		namespace.Usage_memory_percent = percentOf(float32(sum.usageMemory), float32(capacity.Memory))
+		namespace.Usage_cpu_percent = percentOf(float32(sum.usageCpu), float32(capacity.Cpu))
+	}
+	return namespaces
+}
@@ -0,0 +1,48 @@
+package k8s
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSplitNamespaces(t *testing.T) {
+	tests := []struct {
+		list string
+		want []string
+	}{
+		{"", nil},
+		{"team-a", []string{"team-a"}},
+		{"team-a,team-b", []string{"team-a", "team-b"}},
+		{"team-a,team-a", []string{"team-a"}},
+		{" team-a , ,team-b,team-a ", []string{"team-a", "team-b"}},
+	}
+	for _, test := range tests {
+		if got := splitNamespaces(test.list); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("splitNamespaces(%q) = %q, want %q", test.list, got, test.want)
+		}
+	}
+}
+
+func TestByNamespaceSumsRawAmounts(t *testing.T) {
+	// 1.5 MB and 0.5 cores of usage per container, three containers in two pods
+	container := Container{Usage_memory: 1, Usage_cpu: 0.5, raw: rawAmounts{usageMemory: 3 << 19, usageCpu: 500}}
+	pods := []Pod{
+		{Name: "a", Namespace: "team-a", Containers: []Container{container, container}},
+		{Name: "b", Namespace: "team-a", Containers: []Container{container}},
+	}
+
+	namespaces := ByNamespace(pods, ClusterCapacity{Memory: 9 << 20, Cpu: 3000})
+	if len(namespaces) != 1 {
+		t.Fatalf("got %d namespaces, want 1", len(namespaces))
+	}
+	namespace := namespaces[0]
+	if namespace.Pods != 2 || namespace.Usage_memory != 4 || namespace.Usage_cpu != 1.5 {
+		t.Errorf("got %d pods, %d MB and %v cores, want 2 pods, 4 MB and 1.5 cores", namespace.Pods, namespace.Usage_memory, namespace.Usage_cpu)
+	}
+	if namespace.Usage_memory_percent != 50 || namespace.Usage_cpu_percent != 50 {
+		t.Errorf("got %v%% of the memory and %v%% of the cpu, want 50%% of both", namespace.Usage_memory_percent, namespace.Usage_cpu_percent)
+	}
+	if namespace.Capacity_memory != 9 || namespace.Capacity_cpu != 3 {
+		t.Errorf("got a capacity of %d MB and %v cores, want 9 MB and 3 cores", namespace.Capacity_memory, namespace.Capacity_cpu)
+	}
+}
@@ -0,0 +1,97 @@
+package k8s
+
+import "time"
+
+// networkSample is a reading of the rx/tx byte counters from the kubelet summary
+// and the rates since the reading before it
+type networkSample struct {
+	time    time.Time
+	rxBytes int64
+	txBytes int64
+	rxRate  float64 // bytes per second
+	txRate  float64 // bytes per second
+}
+
+// networkRates sets the rates of the current samples against the previous ones of the same key
+// the kubelet refreshes its counters every few seconds, a sample with an unchanged time keeps the last rates
+// a sample seen for the first time or after a counter reset has no rate until the next tick
+func networkRates(previous map[string]networkSample, current map[string]networkSample) {
+	for key, sample := range current {
+		last, ok := previous[key]
+		if !ok {
+			continue
+		}
+
+		elapsed := sample.time.Sub(last.time).Seconds()
+		switch {
+		case elapsed <= 0:
+			sample.rxRate, sample.txRate = last.rxRate, last.txRate
+		case sample.rxBytes < last.rxBytes || sample.txBytes < last.txBytes:
+			// Counters start over when the pod or the node restarts
+		default:
+			sample.rxRate = float64(sample.rxBytes-last.rxBytes) / elapsed
+			sample.txRate = float64(sample.txBytes-last.txBytes) / elapsed
+		}
+		current[key] = sample
+	}
+}
+
+// nodeNetwork returns the network samples of the nodes with the rates since the last refresh
+func (c *Collector) nodeNetwork(summaries map[string]kubeletSummary) map[string]networkSample {
+	current := make(map[string]networkSample, len(summaries))
+	for name, summary := range summaries {
+		if summary.stats == nil {
+			continue
+		}
+		network := summary.stats.Node.Network
+		current[name] = networkSample{time: network.Time, rxBytes: network.RxBytes, txBytes: network.TxBytes}
+	}
+
+	c.networkMu.Lock()
+	defer c.networkMu.Unlock()
+	networkRates(c.nodeSamples, current)
+	c.nodeSamples = current
+	return current
+}
+
+// podNetwork returns the network samples of the pods keyed by namespace/name with the rates since the last refresh
+func (c *Collector) podNetwork(summaries map[string]kubeletSummary) map[string]networkSample {
+	current := make(map[string]networkSample)
+	for _, summary := range summaries {
+		if summary.stats == nil {
+			continue
+		}
+		for _, pod := range summary.stats.Pods {
+			network := pod.Network
+			current[pod.PodRef.Namespace+"/"+pod.PodRef.Name] = networkSample{time: network.Time, rxBytes: network.RxBytes, txBytes: network.TxBytes}
+		}
+	}
+
+	c.networkMu.Lock()
+	defer c.networkMu.Unlock()
+	networkRates(c.podSamples, current)
+	c.podSamples = current
+	return current
+}
+
+// bandwidthBytes converts the --bandwidth in Mbit/s to bytes per second
+func bandwidthBytes(mbits int) int {
+	return mbits * 1000 * 1000 / 8
+}
+
+// setNetwork fills the rates of the node and their usage against the bandwidth
+// the busier direction is taken as the usage as the links are full duplex
+func setNetwork(nodestats *Node, sample networkSample, bandwidth int) {
+	nodestats.Rx_network = sample.rxRate
+	nodestats.Tx_network = sample.txRate
+	nodestats.Capacity_network = bandwidthBytes(bandwidth)
+	busiest := sample.rxRate
+	if sample.txRate > busiest {
+		busiest = sample.txRate
+	}
+	nodestats.Free_network = float64(nodestats.Capacity_network) - busiest
+	nodestats.Usage_network_percent = 0
+	if nodestats.Capacity_network > 0 {
+		nodestats.Usage_network_percent = float32(busiest / float64(nodestats.Capacity_network) * 100)
+	}
+}
@@ -4,43 +4,80 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"os"
-	"path/filepath"
 	"strconv"
-	"strings"
 	"time"
 
 	"github.com/AKSarav/KubeNodeUsage/v3/utils"
 
 	core "k8s.io/api/core/v1"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/client-go/kubernetes"
-	"k8s.io/client-go/tools/clientcmd"
 	"k8s.io/metrics/pkg/apis/metrics/v1beta1"
-	metricsv "k8s.io/metrics/pkg/client/clientset/versioned"
 )
 
+// Node holds the usage of a single node, the json names carry the unit of every value
 type Node struct {
-	Name                 string
-	Capacity_disk        int
-	Capacity_memory      int
-	Capacity_cpu         int
-	Usage_disk           int
-	Usage_memory         int
-	Usage_cpu            float32
-	Free_disk            int
-	Free_memory          int
-	Free_cpu             float32
-	Usage_disk_percent   float32
-	Usage_memory_percent float32
-	Usage_cpu_percent    float32
-	TotalPods            string
-	LabelToDisplay       string
-	Labels               map[string]string
-	Uptime               string
-	Status               string
+	Name                   string            `json:"name"`
+	Capacity_disk          int               `json:"capacity_disk_bytes"`
+	Capacity_memory        int               `json:"capacity_memory_kib"`
+	Capacity_cpu           int               `json:"capacity_cpu_millicores"`
+	Allocatable_disk       int               `json:"allocatable_disk_bytes"`
+	Allocatable_memory     int               `json:"allocatable_memory_kib"`
+	Allocatable_cpu        int               `json:"allocatable_cpu_millicores"`
+	Usage_disk             int               `json:"usage_disk_bytes"`
+	Usage_memory           int               `json:"usage_memory_kib"`
+	Usage_cpu              float32           `json:"usage_cpu_millicores"`
+	Free_disk              int               `json:"free_disk_bytes"`
+	Free_memory            int               `json:"free_memory_kib"`
+	Free_cpu               float32           `json:"free_cpu_millicores"`
+	Usage_disk_percent     float32           `json:"usage_disk_percent"`
+	Usage_memory_percent   float32           `json:"usage_memory_percent"`
+	Usage_cpu_percent      float32           `json:"usage_cpu_percent"`
+	Request_cpu            float32           `json:"request_cpu_millicores"`
+	Request_memory         int               `json:"request_memory_kib"`
+	Limit_cpu              float32           `json:"limit_cpu_millicores"`
+	Limit_memory           int               `json:"limit_memory_kib"`
+	Request_cpu_percent    float32           `json:"request_cpu_percent"`    // against allocatable
+	Request_memory_percent float32           `json:"request_memory_percent"` // against allocatable
+	Limit_cpu_percent      float32           `json:"limit_cpu_percent"`      // above 100 when overcommitted
+	Limit_memory_percent   float32           `json:"limit_memory_percent"`   // above 100 when overcommitted
+	TotalPods              string            `json:"total_pods"`
+	LabelToDisplay         string            `json:"label,omitempty"`
+	Labels                 map[string]string `json:"labels"`
+	Uptime                 string            `json:"uptime"`
+	Status                 string            `json:"status"`
+	Usage_imagefs          int               `json:"usage_imagefs_bytes"`    // container images and writable layers
+	Capacity_imagefs       int               `json:"capacity_imagefs_bytes"` // same as the disk when there is no separate image filesystem
+	Usage_imagefs_percent  float32           `json:"usage_imagefs_percent"`
+	Inodes                 int               `json:"inodes"` // of the node root filesystem
+	Free_inodes            int               `json:"free_inodes"`
+	Usage_inodes_percent   float32           `json:"usage_inodes_percent"`
+	Usage_logs             int               `json:"usage_logs_bytes"` // container logs of the pods on the node
+	Rx_network             float64           `json:"rx_network_bytes_per_second"`
+	Tx_network             float64           `json:"tx_network_bytes_per_second"`
+	Free_network           float64           `json:"free_network_bytes_per_second"`
+	Capacity_network       int               `json:"capacity_network_bytes_per_second"` // the --bandwidth of the nodes
+	Usage_network_percent  float32           `json:"usage_network_percent"`             // busier direction against the bandwidth
+	Disk_source            string            `json:"disk_source,omitempty"`             // where the disk usage comes from
+	Disk_state             string            `json:"disk_state,omitempty"`              // stale or unreachable when the kubelet did not answer
+	Conditions             []Condition       `json:"conditions"`
 }
 
+// Condition is a node or pod condition as shown in the detail pane
+type Condition struct {
+	Type   string `json:"type"`
+	Status string `json:"status"`
+	Reason string `json:"reason,omitempty"`
+}
+
+// Sources of the disk usage, unavailable means we have no real number for it
+// the metrics API has no disk usage, the kubelet summary is the only source
+const (
+	SourceKubelet     = "kubelet-summary"
+	SourceUnavailable = "unavailable"
+)
+
 type Cluster struct {
 	Context string
 	Version string
@@ -50,10 +87,29 @@ type Cluster struct {
 var NodeStatsList []Node
 var K8sinfo Cluster
 
+// ConnectionError is returned when the cluster cannot be reached at startup
+type ConnectionError struct {
+	Cluster Cluster
+	Err     error
+}
+
+func (e *ConnectionError) Error() string {
+	return fmt.Sprintf("unable to establish connection to kubernetes cluster %s (%s): %v", e.Cluster.Context, e.Cluster.URL, e.Err)
+}
+
+func (e *ConnectionError) Unwrap() error {
+	return e.Err
+}
+
 // KubeletStats represents the structure returned by /stats/summary
 type KubeletStats struct {
 	Node struct {
-		NodeName         string `json:"nodeName"`
+		NodeName string `json:"nodeName"`
+		Network  struct {
+			Time    time.Time `json:"time"`
+			RxBytes int64     `json:"rxBytes"`
+			TxBytes int64     `json:"txBytes"`
+		} `json:"network"`
 		SystemContainers []struct {
 			Name      string `json:"name"`
 			UsedBytes int64  `json:"usedBytes"`
@@ -62,11 +118,15 @@ type KubeletStats struct {
 			ImageFs struct {
 				UsedBytes     int64 `json:"usedBytes"`
 				CapacityBytes int64 `json:"capacityBytes"`
+				Inodes        int64 `json:"inodes"`
+				InodesFree    int64 `json:"inodesFree"`
 			} `json:"imageFs"`
 		} `json:"runtime"`
 		Fs struct {
 			UsedBytes     int64 `json:"usedBytes"`
 			CapacityBytes int64 `json:"capacityBytes"`
+			Inodes        int64 `json:"inodes"`
+			InodesFree    int64 `json:"inodesFree"`
 		} `json:"fs"`
 	} `json:"node"`
 	Pods []struct {
@@ -85,189 +145,188 @@ type KubeletStats struct {
 				CapacityBytes int64 `json:"capacityBytes"`
 			} `json:"logs"`
 		} `json:"containers"`
+		Network struct {
+			Time    time.Time `json:"time"`
+			RxBytes int64     `json:"rxBytes"`
+			TxBytes int64     `json:"txBytes"`
+		} `json:"network"`
 		EphemeralStorage struct {
 			UsedBytes     int64 `json:"usedBytes"`
 			CapacityBytes int64 `json:"capacityBytes"`
 		} `json:"ephemeral-storage"`
 		VolumeStats []struct {
-			Name    string `json:"name"`
-			FsStats struct {
-				UsedBytes     int64 `json:"usedBytes"`
-				CapacityBytes int64 `json:"capacityBytes"`
-			} `json:"fs"`
+			// The filesystem stats are inlined in the volume, not nested
+			Name           string `json:"name"`
+			UsedBytes      int64  `json:"usedBytes"`
+			CapacityBytes  int64  `json:"capacityBytes"`
+			AvailableBytes int64  `json:"availableBytes"`
+			PVCRef         *struct {
+				Name      string `json:"name"`
+				Namespace string `json:"namespace"`
+			} `json:"pvcRef,omitempty"`
 		} `json:"volume-stats"`
 	} `json:"pods"`
 }
 
-func ClusterInfo() Cluster {
-	kubeconfig := filepath.Join(os.Getenv("HOME"), ".kube", "config")
-	confvar := clientcmd.GetConfigFromFileOrDie(kubeconfig)
-
+// clusterInfo collects the context, URL and server version for the cluster info header
+func clusterInfo(clients *Clients) (Cluster, error) {
 	K8sinfo := Cluster{}
-	K8sinfo.Context = confvar.CurrentContext
-
-	utils.InitLogger()
-
-	config, err := clientcmd.BuildConfigFromFlags("", kubeconfig)
-	if err != nil {
-		fmt.Println("Not able to access .kube/config file from the Home Directory path: ", kubeconfig)
-		os.Exit(2)
-	}
-
-	mc, err := metricsv.NewForConfig(config)
-	if err != nil {
-		panic(err.Error())
-	}
-
-	K8sinfo.URL = config.Host
+	K8sinfo.Context = clients.Context
+	K8sinfo.URL = clients.Config.Host
 
 	// Validate Version of Server
-	if version, err := mc.ServerVersion(); err != nil {
-		fmt.Println("\n# ERROR: Unable to Establish Connection to Kubernetes Cluster")
-		fmt.Println("# Kubernetes Context:", K8sinfo.Context)
-		fmt.Println("# Kubernetes URL:", K8sinfo.URL)
-		fmt.Println("# Please check your kubernetes configuration and permissions\n")
-		os.Exit(2)
-	} else {
-		K8sinfo.Version = version.String()
+	version, err := clients.Metrics.ServerVersion()
+	if err != nil {
+		return K8sinfo, &ConnectionError{Cluster: K8sinfo, Err: err}
 	}
+	K8sinfo.Version = version.String()
 
-	return K8sinfo
+	return K8sinfo, nil
 
 }
 
 // This Go function takes in node statistics, node information, node metrics, a specific metric, and
 // returns an array of nodes.
 // responsible for collecting memory, cpu, and disk statistics for each node
-func GetMetricsForNode(nodestats *Node, node *core.Node, nm *v1beta1.NodeMetrics, metric string, clientset *kubernetes.Clientset) []Node {
+// metric "all" collects all three of them
+// Free and Usage% are calculated against the capacity or the allocatable resources based on basis
+// stats is the kubelet summary of the node for disk, nil when the kubelet could not be reached
+func GetMetricsForNode(nodestats *Node, node *core.Node, nm *v1beta1.NodeMetrics, metric string, basis string, stats *KubeletStats) []Node {
 
 	NodeMetrics := []Node{}
 
 	switch metric {
 	case "memory":
-		// Ki - Kibibyte - 1024 bytes
-		memcapcity, err := strconv.Atoi(strings.TrimSuffix(node.Status.Capacity.Memory().String(), "Ki"))
-		if err != nil {
-			fmt.Println("Error converting Memory capacity")
-		} else {
-			nodestats.Capacity_memory = memcapcity
-		}
+		// Quantities are converted to Ki - Kibibyte - 1024 bytes
+		// whatever suffix the API server uses (Ki, Mi, Gi, plain bytes, ...)
+		nodestats.Capacity_memory = kibibytes(node.Status.Capacity.Memory())
+		nodestats.Usage_memory = kibibytes(nm.Usage.Memory())
 
-		// output is in Ki - Kibibyte - 1024 bytes
-		if memusage, err := strconv.Atoi(strings.TrimSuffix(nm.Usage.Memory().String(), "Ki")); err != nil {
-			// Try with Mi - Mebibyte - 1024 * 1024 bytes
-			if memusage, err := strconv.Atoi(strings.TrimSuffix(nm.Usage.Memory().String(), "Mi")); err != nil {
-				fmt.Println("Error converting Memory usage", err)
-			} else {
-				nodestats.Usage_memory = memusage
-				nodestats.Free_memory = nodestats.Capacity_memory - nodestats.Usage_memory
-			}
-		} else {
-			nodestats.Usage_memory = memusage
-			nodestats.Free_memory = nodestats.Capacity_memory - nodestats.Usage_memory
-		}
-
-		nodestats.Usage_memory_percent = float32(nodestats.Usage_memory) / float32(nodestats.Capacity_memory) * 100
+		basisMemory := basisFor(basis, nodestats.Capacity_memory, nodestats.Allocatable_memory)
+		nodestats.Free_memory = basisMemory - nodestats.Usage_memory
+		nodestats.Usage_memory_percent = percentOf(float32(nodestats.Usage_memory), float32(basisMemory))
 
 		NodeMetrics = append(NodeMetrics, *nodestats)
 
 	case "cpu":
-		Capacity_cpu, err := strconv.Atoi(node.Status.Capacity.Cpu().String())
-		// Converting to millicore 1 CPU 1000 millicore
-		nodestats.Capacity_cpu = Capacity_cpu * 1000
-		if err != nil {
-			fmt.Println("Error converting CPU capacity")
-		}
-		// fmt.Println("Capacity CPU:", nodestats.Capacity_cpu * 1000)
-
-		cpu_in_nanocore, err := strconv.ParseFloat(strings.TrimSuffix(nm.Usage.Cpu().String(), "n"), 32)
-		if err == nil {
-			cpu_in_millicore := cpu_in_nanocore / 1000000
-			nodestats.Usage_cpu = float32(cpu_in_millicore)
-			nodestats.Free_cpu = float32(nodestats.Capacity_cpu) - nodestats.Usage_cpu
-		} else {
-			// fmt.Println("Error converting CPU usage to millicore")
-		}
+		// Quantities are converted to millicores - 1 CPU 1000 millicore
+		// capacity can be fractional like 3500m and usage is reported in nanocores
+		nodestats.Capacity_cpu = int(node.Status.Capacity.Cpu().MilliValue())
+		nodestats.Usage_cpu = millicores(nm.Usage.Cpu())
 
-		nodestats.Usage_cpu_percent = nodestats.Usage_cpu / float32(nodestats.Capacity_cpu) * 100
-		// fmt.Println("Usage CPU Percent:", nodestats.Usage_cpu_percent)
+		basisCpu := basisFor(basis, nodestats.Capacity_cpu, nodestats.Allocatable_cpu)
+		nodestats.Free_cpu = float32(basisCpu) - nodestats.Usage_cpu
+		nodestats.Usage_cpu_percent = percentOf(nodestats.Usage_cpu, float32(basisCpu))
 
 		NodeMetrics = append(NodeMetrics, *nodestats)
 
 	case "disk":
-		var pods *core.PodList // Declare pods at the start of the case
-
 		// Get disk capacity from ephemeral-storage
 		if capacity, ok := node.Status.Capacity["ephemeral-storage"]; ok {
 			capacityValue := capacity.Value()
 			nodestats.Capacity_disk = int(capacityValue)
 		} else {
-			fmt.Println("No ephemeral-storage capacity found")
-			nodestats.Capacity_disk = -1
+			utils.Logger.Debug("No ephemeral-storage capacity found for node ", node.Name)
+			nodestats.Capacity_disk = 0
 		}
 
-		// Try to get filesystem stats from kubelet API
-		if stats, err := getKubeletStats(clientset, node); err == nil {
-			// Use the filesystem stats from kubelet
+		// Use the filesystem stats from the kubelet summary when we have it
+		// and report the usage as unavailable otherwise
+		nodestats.Usage_disk = 0
+		nodestats.Disk_source = SourceUnavailable
+		setDiskBreakdown(nodestats, stats)
+		if stats != nil {
 			nodestats.Usage_disk = int(stats.Node.Fs.UsedBytes)
+			nodestats.Disk_source = SourceKubelet
 			if stats.Node.Fs.CapacityBytes > 0 {
 				// If kubelet reports capacity, use that instead
 				nodestats.Capacity_disk = int(stats.Node.Fs.CapacityBytes)
 			}
-		} else {
-			// Fall back to metrics API
-			if fsStats := nm.Usage.StorageEphemeral(); fsStats != nil {
-				usageValue := fsStats.Value()
-				if usageValue > 0 {
-					nodestats.Usage_disk = int(usageValue)
-				}
-			}
+		}
 
-			// If still no usage data, try to estimate from pods
-			if nodestats.Usage_disk == 0 {
-				var err error
-				pods, err = clientset.CoreV1().Pods("").List(context.TODO(), v1.ListOptions{
-					FieldSelector: fmt.Sprintf("spec.nodeName=%s", node.Name),
-				})
-				if err == nil {
-					var podStorageUsage int64 = 0
-					for _, pod := range pods.Items {
-						// Get actual storage usage from pod status if available
-						for _, containerStatus := range pod.Status.ContainerStatuses {
-							if containerStatus.RestartCount > 0 {
-								podStorageUsage += 10 * 1024 * 1024 // 10MB per restart
-							}
-						}
-						// Add volume sizes if available
-						for _, volume := range pod.Spec.Volumes {
-							if volume.EmptyDir != nil && volume.EmptyDir.SizeLimit != nil {
-								sizeLimit := volume.EmptyDir.SizeLimit.Value()
-								podStorageUsage += sizeLimit / 2
-							}
-						}
-					}
-					nodestats.Usage_disk = int(podStorageUsage)
-				}
-			}
+		// Without a capacity there is no free space or percent either, the usage is as unknown as the rest
+		basisDisk := basisFor(basis, nodestats.Capacity_disk, nodestats.Allocatable_disk)
+		if nodestats.Disk_source != SourceUnavailable && basisDisk <= 0 {
+			utils.Logger.Debug("Invalid disk capacity for node ", node.Name)
+			nodestats.Disk_source = SourceUnavailable
 		}
 
-		if nodestats.Capacity_disk > 0 {
-			nodestats.Free_disk = nodestats.Capacity_disk - nodestats.Usage_disk
-			nodestats.Usage_disk_percent = float32(nodestats.Usage_disk) / float32(nodestats.Capacity_disk) * 100
-		} else {
-			fmt.Println("Invalid disk capacity")
-			nodestats.Usage_disk = -1
-			nodestats.Free_disk = -1
+		// Unknown values are left at zero, the source tells they are not real
+		if nodestats.Disk_source == SourceUnavailable {
+			utils.Logger.Debug("No disk usage available for node ", node.Name)
+			nodestats.Usage_disk = 0
+			nodestats.Free_disk = 0
 			nodestats.Usage_disk_percent = 0
+		} else {
+			nodestats.Free_disk = basisDisk - nodestats.Usage_disk
+			nodestats.Usage_disk_percent = float32(nodestats.Usage_disk) / float32(basisDisk) * 100
 		}
 
 		NodeMetrics = append(NodeMetrics, *nodestats)
+
+	case "network":
+		// The rates are diffed between refreshes by the collector, see setNetwork
+		NodeMetrics = append(NodeMetrics, *nodestats)
+
+	case "all":
+		// Collect every metric into the same node for the combined view
+		for _, each := range []string{"memory", "cpu", "disk"} {
+			GetMetricsForNode(nodestats, node, nm, each, basis, stats)
+		}
+		NodeMetrics = append(NodeMetrics, *nodestats)
 	}
 	return NodeMetrics
 }
 
+// setDiskBreakdown fills the image filesystem, inode and log usage from the kubelet summary
+// they are only known from the kubelet, so they are zero without a summary
+func setDiskBreakdown(nodestats *Node, stats *KubeletStats) {
+	nodestats.Usage_imagefs, nodestats.Capacity_imagefs, nodestats.Usage_imagefs_percent = 0, 0, 0
+	nodestats.Inodes, nodestats.Free_inodes, nodestats.Usage_inodes_percent = 0, 0, 0
+	nodestats.Usage_logs = 0
+	if stats == nil {
+		return
+	}
+
+	imageFs := stats.Node.Runtime.ImageFs
+	nodestats.Usage_imagefs = int(imageFs.UsedBytes)
+	nodestats.Capacity_imagefs = int(imageFs.CapacityBytes)
+	if imageFs.CapacityBytes > 0 {
+		nodestats.Usage_imagefs_percent = float32(imageFs.UsedBytes) / float32(imageFs.CapacityBytes) * 100
+	}
+
+	fs := stats.Node.Fs
+	nodestats.Inodes = int(fs.Inodes)
+	nodestats.Free_inodes = int(fs.InodesFree)
+	if fs.Inodes > 0 {
+		nodestats.Usage_inodes_percent = float32(fs.Inodes-fs.InodesFree) / float32(fs.Inodes) * 100
+	}
+
+	for _, pod := range stats.Pods {
+		for _, container := range pod.Containers {
+			nodestats.Usage_logs += int(container.Logs.UsedBytes)
+		}
+	}
+}
+
+// basisFor returns the capacity or the allocatable value to calculate Free and Usage% against
+// nodes that do not report allocatable fall back to the capacity
+func basisFor(basis string, capacity int, allocatable int) int {
+	if basis == "allocatable" && allocatable > 0 {
+		return allocatable
+	}
+	return capacity
+}
+
+// Max returns the memory (Ki), cpu (millicores) and disk (bytes) that Free and Usage% are calculated against
+func (n Node) Max(basis string) (memory int, cpu int, disk int) {
+	return basisFor(basis, n.Capacity_memory, n.Allocatable_memory),
+		basisFor(basis, n.Capacity_cpu, n.Allocatable_cpu),
+		basisFor(basis, n.Capacity_disk, n.Allocatable_disk)
+}
+
 // getKubeletStats retrieves disk usage statistics from the kubelet's /stats/summary endpoint
-func getKubeletStats(clientset *kubernetes.Clientset, node *core.Node) (*KubeletStats, error) {
+func getKubeletStats(ctx context.Context, clientset *kubernetes.Clientset, node *core.Node) (*KubeletStats, error) {
 	// Get the node's internal IP
 	var nodeIP string
 	for _, addr := range node.Status.Addresses {
@@ -288,7 +347,7 @@ func getKubeletStats(clientset *kubernetes.Clientset, node *core.Node) (*Kubelet
 		Suffix("stats/summary")
 
 	// Get raw bytes
-	raw, err := request.DoRaw(context.TODO())
+	raw, err := request.DoRaw(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get kubelet stats: %v", err)
 	}
@@ -302,93 +361,54 @@ func getKubeletStats(clientset *kubernetes.Clientset, node *core.Node) (*Kubelet
 	return result, nil
 }
 
-// getPodStats retrieves disk usage statistics for a specific pod from the kubelet's /stats/summary endpoint
-func getPodStats(clientset *kubernetes.Clientset, node *core.Node, podName string, podNamespace string) (int64, error) {
-	stats, err := getKubeletStats(clientset, node)
-	if err != nil {
-		return 0, fmt.Errorf("failed to get kubelet stats: %v", err)
-	}
-
+// getPodStats retrieves the disk usage of a specific pod from the /stats/summary of its node
+// the ephemeral storage of the kubelet already covers the rootfs and logs of the containers
+// and the local volumes, the persistent volumes are left out of it
+func getPodStats(stats *KubeletStats, podName string, podNamespace string) (int64, error) {
 	// Find the pod in the stats
 	for _, pod := range stats.Pods {
 		if pod.PodRef.Name == podName && pod.PodRef.Namespace == podNamespace {
-			var totalUsage int64 = 0
-
-			// Add ephemeral storage usage
-			totalUsage += pod.EphemeralStorage.UsedBytes
-
-			// Add container storage usage
-			for _, container := range pod.Containers {
-				containerUsage := container.Rootfs.UsedBytes + container.Logs.UsedBytes
-				totalUsage += containerUsage
-			}
-
-			// Add volume storage usage
-			for _, volume := range pod.VolumeStats {
-				volumeUsage := volume.FsStats.UsedBytes
-				totalUsage += volumeUsage
-			}
-
-			// Add filesystem usage from the node's stats for this pod
-			// We'll estimate this as 0.1% of the node's total filesystem usage
-			if stats.Node.Fs.UsedBytes > 0 {
-				fsUsage := stats.Node.Fs.UsedBytes / 1000 // 0.1% of node's filesystem usage
-				totalUsage += fsUsage
-			}
-
-			// Add a base amount for pod overhead (metadata, etc.)
-			baseOverhead := int64(1 * 1024 * 1024) // 1MB base overhead
-			totalUsage += baseOverhead
-
-			return totalUsage, nil
+			return pod.EphemeralStorage.UsedBytes, nil
 		}
 	}
 
 	return 0, fmt.Errorf("pod %s/%s not found in kubelet stats", podNamespace, podName)
 }
 
-func Nodes(inputs *utils.Inputs) (NodeStatsList []Node) {
+// Nodes collects the node usage for the metric chosen in the inputs
+func (c *Collector) Nodes() ([]Node, error) {
+	return c.NodesFor(c.Inputs)
+}
 
+// NodesFor collects the node usage with other inputs than the ones of the collector
+// like the snapshot of its inputs the node view fetches with in the background
+func (c *Collector) NodesFor(inputs *utils.Inputs) (NodeStatsList []Node, err error) {
 	metric := inputs.Metrics
 
-	utils.InitLogger()
-	kubeconfig := filepath.Join(os.Getenv("HOME"), ".kube", "config")
-
-	config, err := clientcmd.BuildConfigFromFlags("", kubeconfig)
-	if err != nil {
-		fmt.Println("Not able to access .kube/config file from the Home Directory path: ", kubeconfig)
-		os.Exit(2)
-	}
-
-	clientset, err := kubernetes.NewForConfig(config)
+	// The label filter is left to the API server, only the matching nodes are fetched
+	selector, err := LabelSelector(inputs.FilterLabel)
 	if err != nil {
-		panic(err.Error())
+		return nil, err
 	}
 
-	mc, err := metricsv.NewForConfig(config)
-	if err != nil {
-		panic(err.Error())
-	}
+	mc := c.clients.Metrics
 
 	// To fetch kubectl top nodes metrics
-	nodeMetrics, err := mc.MetricsV1beta1().NodeMetricses().List(context.TODO(), v1.ListOptions{})
+	nodeMetrics, err := mc.MetricsV1beta1().NodeMetricses().List(context.TODO(), v1.ListOptions{LabelSelector: selector.String()})
 	if err != nil {
-		fmt.Println("Unable to Get NodeMetrics. Is Metrics Server running ?")
-		os.Exit(2)
+		return nil, fmt.Errorf("unable to get node metrics, is metrics server running? %v", err)
 	}
 
 	// To fetch kubectl get nodes information
-	nodes, err := clientset.CoreV1().Nodes().List(context.TODO(), v1.ListOptions{})
+	nodes, err := c.listNodes(selector)
 	if err != nil {
-		fmt.Println("Failed to Get Nodes")
-		panic(err.Error())
+		return nil, fmt.Errorf("failed to get nodes: %v", err)
 	}
 
-	// To fetch kubectl get pods information
-	pods, err := clientset.CoreV1().Pods("").List(context.TODO(), v1.ListOptions{})
+	// To fetch kubectl get pods information - all of them in every namespace, the selector is for the nodes
+	pods, err := c.listPods(nil, "", labels.Everything())
 	if err != nil {
-		fmt.Println("Failed to Get Pods")
-		panic(err.Error())
+		return nil, fmt.Errorf("failed to get pods: %v", err)
 	}
 
 	// output comes in this format
@@ -404,9 +424,23 @@ func Nodes(inputs *utils.Inputs) (NodeStatsList []Node) {
 
 	nodestats := Node{}
 
+	// Requests and Limits of the pods summed per node
+	allocations := nodeAllocations(pods)
+
+	// Kubelet summaries for the disk usage, fetched in parallel before walking the nodes
+	var summaries map[string]kubeletSummary
+	var network map[string]networkSample
+	if metric == "disk" || metric == "all" || metric == "network" {
+		// only the unselected list has every node, the summaries of the missing ones can go then
+		summaries = c.kubeletSummaries(nodes, selector.Empty())
+	}
+	if metric == "network" {
+		network = c.nodeNetwork(summaries)
+	}
+
 	// Parsing Every Node and collecting information
 	for _, nm := range nodeMetrics.Items {
-		for _, node := range nodes.Items {
+		for _, node := range nodes {
 			if node.Name == nm.Name {
 				// fmt.Println("Node Name:", node.Name)
 				nodestats.Name = node.Name
@@ -422,6 +456,15 @@ func Nodes(inputs *utils.Inputs) (NodeStatsList []Node) {
 					}
 				}
 
+				nodestats.Conditions = nil
+				for _, condition := range node.Status.Conditions {
+					nodestats.Conditions = append(nodestats.Conditions, Condition{
+						Type:   string(condition.Type),
+						Status: string(condition.Status),
+						Reason: condition.Reason,
+					})
+				}
+
 				// capture Uptime
 				uptimeDuration := time.Since(node.CreationTimestamp.Time)
 
@@ -440,13 +483,21 @@ func Nodes(inputs *utils.Inputs) (NodeStatsList []Node) {
 
 				// Counting Total Pods in the Node
 				var totalpods int
-				for _, pod := range pods.Items {
+				for _, pod := range pods {
 					if pod.Spec.NodeName == node.Name {
 						totalpods++
 					}
 				}
 				nodestats.TotalPods = strconv.Itoa(totalpods)
 
+				// Requested and Limits against the allocatable resources
+				setAllocation(&nodestats, node, allocations[node.Name])
+
+				// Allocatable is the capacity minus the kube-reserved and system-reserved resources
+				nodestats.Allocatable_memory = kibibytes(node.Status.Allocatable.Memory())
+				nodestats.Allocatable_cpu = int(node.Status.Allocatable.Cpu().MilliValue())
+				nodestats.Allocatable_disk = int(node.Status.Allocatable.StorageEphemeral().Value())
+
 				// Display Label if provided - Logic
 				if inputs.LabelToDisplay != "" {
 					// check if the label exists in the node - if not, set output to "Not Found"
@@ -460,7 +511,13 @@ func Nodes(inputs *utils.Inputs) (NodeStatsList []Node) {
 				// Collect all the labels and store in a map
 				nodestats.Labels = node.Labels
 
-				NodeStatsList = append(NodeStatsList, GetMetricsForNode(&nodestats, &node, &nm, metric, clientset)[0])
+				summary := summaries[node.Name]
+				nodestats.Disk_state = summary.state()
+				if metric == "network" {
+					setNetwork(&nodestats, network[node.Name], inputs.Bandwidth)
+				}
+
+				NodeStatsList = append(NodeStatsList, GetMetricsForNode(&nodestats, node, &nm, metric, inputs.Basis, summary.stats)[0])
 
 			}
 
@@ -468,6 +525,6 @@ func Nodes(inputs *utils.Inputs) (NodeStatsList []Node) {
 	}
 
 	utils.Logger.Debug(NodeStatsList)
-	return NodeStatsList
+	return NodeStatsList, nil
 
 }
@@ -0,0 +1,46 @@
+package k8s
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/AKSarav/KubeNodeUsage/v3/utils"
+)
+
+func TestGetPodStatsEphemeralOnly(t *testing.T) {
+	// The ephemeral storage already holds the rootfs, the logs and the local volumes, the claim is not part of it
+	summary := `{"pods": [{
+		"podRef": {"name": "web", "namespace": "team-a"},
+		"ephemeral-storage": {"usedBytes": 3000},
+		"containers": [{"rootfs": {"usedBytes": 1000}, "logs": {"usedBytes": 500}}],
+		"volume-stats": [{"name": "cache", "usedBytes": 1500}, {"name": "data", "usedBytes": 50000, "pvcRef": {"name": "data", "namespace": "team-a"}}]
+	}]}`
+	var stats KubeletStats
+	if err := json.Unmarshal([]byte(summary), &stats); err != nil {
+		t.Fatal(err)
+	}
+
+	used, err := getPodStats(&stats, "web", "team-a")
+	if err != nil || used != 3000 {
+		t.Errorf("got %d bytes and %v, want 3000 bytes", used, err)
+	}
+	if _, err := getPodStats(&stats, "web", "team-b"); err == nil {
+		t.Errorf("got no error for a pod missing from the summary")
+	}
+}
+
+func TestFilterColorSkipsUnavailableDisk(t *testing.T) {
+	utils.InitLogger()
+	nodes := []Node{
+		{Name: "known", Disk_source: SourceKubelet, Usage_disk_percent: 10},
+		{Name: "unknown", Disk_source: SourceUnavailable},
+	}
+
+	green := Filter(nodes, &utils.Inputs{Metrics: "disk", FilterColor: "green"})
+	if len(green) != 1 || green[0].Name != "known" {
+		t.Errorf("got %v, want the node with a known disk usage only", green)
+	}
+	if all := Filter(nodes, &utils.Inputs{Metrics: "disk"}); len(all) != 2 {
+		t.Errorf("got %d nodes without a color filter, want 2", len(all))
+	}
+}
@@ -1,96 +1,133 @@
 package k8s
 
 import (
-	"context"
 	"fmt"
-	"os"
-	"path/filepath"
 
 	"github.com/AKSarav/KubeNodeUsage/v3/utils"
 
 	core "k8s.io/api/core/v1"
-	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/client-go/kubernetes"
-	"k8s.io/client-go/tools/clientcmd"
-	metricsv "k8s.io/metrics/pkg/client/clientset/versioned"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/metrics/pkg/apis/metrics/v1beta1"
 )
 
+// Pod holds the usage of a single pod, the json names carry the unit of every value
 type Pod struct {
-	Name                 string
-	Namespace            string
-	NodeName             string
-	Capacity_memory      int
-	Capacity_cpu         int
-	Usage_memory         int
-	Usage_cpu            float32
-	Request_memory       int
-	Request_cpu          float32
-	Limit_memory         int
-	Limit_cpu            float32
-	Usage_memory_percent float32
-	Usage_cpu_percent    float32
-	Usage_disk           float64 // Total disk usage in MB
-	Node_disk_capacity   float64 // Node's total disk capacity in GB
-	Usage_disk_percent   float32 // Disk usage percentage
-	Status               string
-	LabelToDisplay       string
-	Labels               map[string]string
+	Name                  string            `json:"name"`
+	Namespace             string            `json:"namespace"`
+	NodeName              string            `json:"node"`
+	Capacity_memory       int               `json:"capacity_memory_mib"`
+	Capacity_cpu          int               `json:"capacity_cpu_millicores"`
+	Usage_memory          int               `json:"usage_memory_mib"`
+	Usage_cpu             float32           `json:"usage_cpu_cores"`
+	Request_memory        int               `json:"request_memory_mib"`
+	Request_cpu           float32           `json:"request_cpu_cores"`
+	Limit_memory          int               `json:"limit_memory_mib"`
+	Limit_cpu             float32           `json:"limit_cpu_cores"`
+	Usage_memory_percent  float32           `json:"usage_memory_percent"`
+	Usage_cpu_percent     float32           `json:"usage_cpu_percent"`
+	Usage_disk            float64           `json:"usage_disk_mib"`         // Total disk usage in MB
+	Node_disk_capacity    float64           `json:"node_disk_capacity_gib"` // Node's total disk capacity in GB
+	Usage_disk_percent    float32           `json:"usage_disk_percent"`     // Disk usage percentage
+	Disk_source           string            `json:"disk_source,omitempty"`  // where the disk usage comes from
+	Rx_network            float64           `json:"rx_network_bytes_per_second"`
+	Tx_network            float64           `json:"tx_network_bytes_per_second"`
+	Usage_network_percent float32           `json:"usage_network_percent"` // busier direction against the node bandwidth
+	Status                string            `json:"status"`
+	LabelToDisplay        string            `json:"label,omitempty"`
+	Labels                map[string]string `json:"labels"`
+	Owner                 string            `json:"owner,omitempty"` // kind/name of the controller of the pod
+	Restarts              int               `json:"restarts"`        // of all the containers
+	Containers            []Container       `json:"containers"`
+	Conditions            []Condition       `json:"conditions"`
 }
 
-var PodStatsList []Pod
+// Container holds the usage of a single container against its own requests and limits
+// it is filled for every metric, the units are the ones of the pod
+type Container struct {
+	Name           string  `json:"name"`
+	Usage_memory   int     `json:"usage_memory_mib"`
+	Usage_cpu      float32 `json:"usage_cpu_cores"`
+	Request_memory int     `json:"request_memory_mib"`
+	Request_cpu    float32 `json:"request_cpu_cores"`
+	Limit_memory   int     `json:"limit_memory_mib"`
+	Limit_cpu      float32 `json:"limit_cpu_cores"`
+	Restarts       int     `json:"restarts"`
+	State          string  `json:"state"` // Running, or the reason it is waiting or terminated
+
+	raw rawAmounts // the values above before the rounding, summed up per namespace
+}
 
-func Pods(inputs *utils.Inputs) (PodStatsList []Pod) {
-	metric := inputs.Metrics
+// rawAmounts are the memory in bytes and the cpu in millicores of a container
+type rawAmounts struct {
+	usageMemory, requestMemory, limitMemory int64
+	usageCpu, requestCpu, limitCpu          int64
+}
 
-	utils.InitLogger()
-	kubeconfig := filepath.Join(os.Getenv("HOME"), ".kube", "config")
+var PodStatsList []Pod
 
-	config, err := clientcmd.BuildConfigFromFlags("", kubeconfig)
-	if err != nil {
-		fmt.Println("Not able to access .kube/config file from the Home Directory path: ", kubeconfig)
-		os.Exit(2)
-	}
+// Pods collects the pod usage for the metric chosen in the inputs
+func (c *Collector) Pods() ([]Pod, error) {
+	return c.PodsFor(c.Inputs, "")
+}
 
-	clientset, err := kubernetes.NewForConfig(config)
-	if err != nil {
-		panic(err.Error())
-	}
+// PodsFor collects the pod usage with other inputs than the ones of the collector
+// like the pods of a node opened from the node view, which has a metric and filters of its own
+// a node collects only the pods scheduled on it and only its kubelet summary, "" is every node
+func (c *Collector) PodsFor(inputs *utils.Inputs, node string) (PodStatsList []Pod, err error) {
+	metric := inputs.Metrics
 
-	mc, err := metricsv.NewForConfig(config)
+	// The label filter is left to the API server, only the matching pods are fetched
+	selector, err := LabelSelector(inputs.FilterLabel)
 	if err != nil {
-		panic(err.Error())
+		return nil, err
 	}
 
-	// To fetch kubectl top pods metrics
-	podMetrics, err := mc.MetricsV1beta1().PodMetricses("").List(context.TODO(), v1.ListOptions{})
+	// To fetch kubectl top pods metrics, only in the namespaces asked for
+	// the metrics API has no node selector, the metrics are matched with the pods of the node below
+	podMetrics, err := c.scopedPodMetrics(inputs, selector)
 	if err != nil {
-		fmt.Println("Unable to Get PodMetrics. Is Metrics Server running?")
-		os.Exit(2)
+		return nil, fmt.Errorf("unable to get pod metrics, is metrics server running? %v", err)
 	}
 
 	// To fetch kubectl get pods information
-	pods, err := clientset.CoreV1().Pods("").List(context.TODO(), v1.ListOptions{})
+	pods, err := c.scopedPods(inputs, node, selector)
 	if err != nil {
-		fmt.Println("Failed to Get Pods")
-		panic(err.Error())
+		return nil, fmt.Errorf("failed to get pods: %v", err)
 	}
 
-	// To fetch node information for capacity context
-	nodes, err := clientset.CoreV1().Nodes().List(context.TODO(), v1.ListOptions{})
+	// To fetch node information for capacity context - all of them, the selector is for the pods
+	nodes, err := c.listNodes(labels.Everything())
 	if err != nil {
-		fmt.Println("Failed to Get Nodes")
-		panic(err.Error())
+		return nil, fmt.Errorf("failed to get nodes: %v", err)
 	}
 
 	// Create a map of node names to node objects
 	nodeMap := make(map[string]*core.Node)
-	for i := range nodes.Items {
-		nodeMap[nodes.Items[i].Name] = &nodes.Items[i]
+	for _, node := range nodes {
+		nodeMap[node.Name] = node
+	}
+
+	// Fetch the kubelet summary once per node hosting pods instead of once per pod
+	var summaries map[string]kubeletSummary
+	var network map[string]networkSample
+	if metric == "disk" || metric == "network" {
+		var hosts []*core.Node
+		seen := make(map[string]bool)
+		for _, pod := range pods {
+			if node, exists := nodeMap[pod.Spec.NodeName]; exists && !seen[node.Name] {
+				seen[node.Name] = true
+				hosts = append(hosts, node)
+			}
+		}
+		summaries = c.kubeletSummaries(hosts, false)
+	}
+	if metric == "network" {
+		network = c.podNetwork(summaries)
 	}
 
 	// Parsing Every Pod and collecting information
-	for _, pod := range pods.Items {
-		for _, pm := range podMetrics.Items {
+	for _, pod := range pods {
+		for _, pm := range podMetrics {
 			if pod.Name == pm.Name && pod.Namespace == pm.Namespace {
 				podstats := Pod{}
 				podstats.Name = pod.Name
@@ -113,18 +150,18 @@ func Pods(inputs *utils.Inputs) (PodStatsList []Pod) {
 					var totalMemLimit int64 = 0
 
 					for _, container := range pm.Containers {
-						memUsage, _ := container.Usage.Memory().AsInt64()
+						memUsage := container.Usage.Memory().Value()
 						totalMemUsage += memUsage
 					}
 
 					for _, container := range pod.Spec.Containers {
 						if container.Resources.Requests.Memory() != nil {
-							memReq, _ := container.Resources.Requests.Memory().AsInt64()
+							memReq := container.Resources.Requests.Memory().Value()
 							totalMemRequest += memReq
 						}
 
 						if container.Resources.Limits.Memory() != nil {
-							memLimit, _ := container.Resources.Limits.Memory().AsInt64()
+							memLimit := container.Resources.Limits.Memory().Value()
 							totalMemLimit += memLimit
 						}
 					}
@@ -134,9 +171,12 @@ func Pods(inputs *utils.Inputs) (PodStatsList []Pod) {
 					podstats.Request_memory = int(totalMemRequest / (1024 * 1024))
 					podstats.Limit_memory = int(totalMemLimit / (1024 * 1024))
 
-					// If pod is on a node, get node capacity for context
+					// If pod is on a node, get node capacity (or allocatable with --basis) for context
 					if node, exists := nodeMap[pod.Spec.NodeName]; exists {
-						nodeCap, _ := node.Status.Capacity.Memory().AsInt64()
+						nodeCap := node.Status.Capacity.Memory().Value()
+						if inputs.Basis == "allocatable" && !node.Status.Allocatable.Memory().IsZero() {
+							nodeCap = node.Status.Allocatable.Memory().Value()
+						}
 						podstats.Capacity_memory = int(nodeCap / (1024 * 1024))
 					}
 
@@ -175,30 +215,54 @@ func Pods(inputs *utils.Inputs) (PodStatsList []Pod) {
 					podstats.Request_cpu = totalCpuRequest / 1000
 					podstats.Limit_cpu = totalCpuLimit / 1000
 
-					// If pod is on a node, get node capacity for context
+					// If pod is on a node, get node capacity (or allocatable with --basis) for context
 					if node, exists := nodeMap[pod.Spec.NodeName]; exists {
 						nodeCap := node.Status.Capacity.Cpu().MilliValue()
+						if inputs.Basis == "allocatable" && !node.Status.Allocatable.Cpu().IsZero() {
+							nodeCap = node.Status.Allocatable.Cpu().MilliValue()
+						}
 						podstats.Capacity_cpu = int(nodeCap)
 					}
 
 					// Calculate percentage based on limit or node capacity
 					if podstats.Limit_cpu > 0 {
 						podstats.Usage_cpu_percent = (podstats.Usage_cpu / podstats.Limit_cpu) * 100
-					} else if podstats.Capacity_cpu > 0 {
-						podstats.Usage_cpu_percent = (podstats.Usage_cpu / float32(podstats.Capacity_cpu/1000)) * 100
+					} else {
+						podstats.Usage_cpu_percent = percentOf(podstats.Usage_cpu, float32(podstats.Capacity_cpu)/1000)
 					}
 
 				case "disk":
-					// Try to get disk usage from kubelet stats first
-					if diskUsage, err := getPodStats(clientset, node, pod.Name, pod.Namespace); err == nil {
+					// Get and store node's disk capacity in GB
+					if storage, ok := node.Status.Capacity["ephemeral-storage"]; ok {
+						storageCapacity := storage.Value()
+						podstats.Node_disk_capacity = float64(storageCapacity) / float64(1024*1024*1024) // Convert to GB
+					}
+
+					// Take the disk usage from the kubelet summary of the node
+					// the metrics API has no disk usage for pods, so there is nothing to fall back to
+					podstats.Disk_source = SourceUnavailable
+					summary := summaries[node.Name]
+					if summary.stats == nil {
+						utils.Logger.Debug("No kubelet stats for node ", node.Name, ": ", summary.err)
+					} else if diskUsage, err := getPodStats(summary.stats, pod.Name, pod.Namespace); err == nil {
 						// Convert bytes to MB
 						podstats.Usage_disk = float64(diskUsage) / float64(1024*1024)
+						podstats.Disk_source = SourceKubelet
+					} else {
+						utils.Logger.Debug(err)
+					}
 
-						// Get and store node's disk capacity in GB
-						if storage, ok := node.Status.Capacity["ephemeral-storage"]; ok {
-							storageCapacity := storage.Value()
-							podstats.Node_disk_capacity = float64(storageCapacity) / float64(1024*1024*1024) // Convert to GB
-						}
+				case "network":
+					// Rates since the last refresh, against the bandwidth of the node link
+					sample := network[pod.Namespace+"/"+pod.Name]
+					podstats.Rx_network = sample.rxRate
+					podstats.Tx_network = sample.txRate
+					busiest := sample.rxRate
+					if sample.txRate > busiest {
+						busiest = sample.txRate
+					}
+					if bandwidth := bandwidthBytes(inputs.Bandwidth); bandwidth > 0 {
+						podstats.Usage_network_percent = float32(busiest / float64(bandwidth) * 100)
 					}
 				}
 
@@ -214,11 +278,90 @@ func Pods(inputs *utils.Inputs) (PodStatsList []Pod) {
 				// Collect all labels
 				podstats.Labels = pod.Labels
 
+				// Containers, owner and conditions for the detail pane
+				podstats.Containers, podstats.Restarts = podContainers(pod, &pm)
+				podstats.Owner = podOwner(pod)
+				for _, condition := range pod.Status.Conditions {
+					podstats.Conditions = append(podstats.Conditions, Condition{
+						Type:   string(condition.Type),
+						Status: string(condition.Status),
+						Reason: condition.Reason,
+					})
+				}
+
 				PodStatsList = append(PodStatsList, podstats)
 			}
 		}
 	}
 
 	utils.Logger.Debug(PodStatsList)
-	return PodStatsList
+	return PodStatsList, nil
+}
+
+// podContainers pairs the usage of every container with its requests, limits and restarts
+// and returns the restarts of the pod as well
+func podContainers(pod *core.Pod, pm *v1beta1.PodMetrics) (containers []Container, restarts int) {
+	usage := make(map[string]core.ResourceList)
+	for _, container := range pm.Containers {
+		usage[container.Name] = container.Usage
+	}
+
+	statuses := make(map[string]core.ContainerStatus)
+	for _, status := range pod.Status.ContainerStatuses {
+		statuses[status.Name] = status
+	}
+
+	for _, spec := range pod.Spec.Containers {
+		used := usage[spec.Name]
+
+		raw := rawAmounts{
+			usageMemory:   used.Memory().Value(),
+			requestMemory: spec.Resources.Requests.Memory().Value(),
+			limitMemory:   spec.Resources.Limits.Memory().Value(),
+			usageCpu:      used.Cpu().MilliValue(),
+			requestCpu:    spec.Resources.Requests.Cpu().MilliValue(),
+			limitCpu:      spec.Resources.Limits.Cpu().MilliValue(),
+		}
+
+		// Memory in MB and cpu in cores like the pod
+		container := Container{
+			Name:           spec.Name,
+			Usage_memory:   int(raw.usageMemory / (1024 * 1024)),
+			Usage_cpu:      float32(raw.usageCpu) / 1000,
+			Request_memory: int(raw.requestMemory / (1024 * 1024)),
+			Request_cpu:    float32(raw.requestCpu) / 1000,
+			Limit_memory:   int(raw.limitMemory / (1024 * 1024)),
+			Limit_cpu:      float32(raw.limitCpu) / 1000,
+			raw:            raw,
+		}
+
+		if status, ok := statuses[spec.Name]; ok {
+			container.Restarts = int(status.RestartCount)
+			switch {
+			case status.State.Running != nil:
+				container.State = "Running"
+			case status.State.Waiting != nil:
+				container.State = status.State.Waiting.Reason
+			case status.State.Terminated != nil:
+				container.State = status.State.Terminated.Reason
+			}
+		}
+
+		restarts += container.Restarts
+		containers = append(containers, container)
+	}
+	return containers, restarts
+}
+
+// podOwner returns the kind/name of the controller of the pod, or of its first owner
+func podOwner(pod *core.Pod) string {
+	for _, ref := range pod.OwnerReferences {
+		if ref.Controller != nil && *ref.Controller {
+			return ref.Kind + "/" + ref.Name
+		}
+	}
+	if len(pod.OwnerReferences) > 0 {
+		return pod.OwnerReferences[0].Kind + "/" + pod.OwnerReferences[0].Name
+	}
+	return ""
 }
@@ -0,0 +1,130 @@
+package k8s
+
+import (
+	core "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// kibibytes converts a memory or storage quantity to Ki whatever its suffix is
+func kibibytes(q *resource.Quantity) int {
+	return int(q.Value() / 1024)
+}
+
+// millicores converts a cpu quantity to millicores keeping the fraction of nanocore usage
+func millicores(q *resource.Quantity) float32 {
+	return float32(q.AsApproximateFloat64() * 1000)
+}
+
+// percentOf returns value as a percent of total, zero when total is unknown
+func percentOf(value float32, total float32) float32 {
+	if total <= 0 {
+		return 0
+	}
+	return value / total * 100
+}
+
+// allocation holds the summed requests and limits of the pods scheduled on a node
+type allocation struct {
+	requests core.ResourceList
+	limits   core.ResourceList
+}
+
+func addResources(total core.ResourceList, add core.ResourceList) {
+	for name, quantity := range add {
+		if value, ok := total[name]; ok {
+			value.Add(quantity)
+			total[name] = value
+		} else {
+			total[name] = quantity.DeepCopy()
+		}
+	}
+}
+
+func maxResources(total core.ResourceList, other core.ResourceList) {
+	for name, quantity := range other {
+		if value, ok := total[name]; !ok || quantity.Cmp(value) > 0 {
+			total[name] = quantity.DeepCopy()
+		}
+	}
+}
+
+// podRequestsAndLimits returns the requests and limits of a pod the way the scheduler counts them
+// containers are summed, an init container only counts when it asks for more than that
+// and the pod overhead is added on top
+func podRequestsAndLimits(pod *core.Pod) (core.ResourceList, core.ResourceList) {
+	requests, limits := core.ResourceList{}, core.ResourceList{}
+
+	for _, container := range pod.Spec.Containers {
+		addResources(requests, container.Resources.Requests)
+		addResources(limits, container.Resources.Limits)
+	}
+
+	for _, container := range pod.Spec.InitContainers {
+		maxResources(requests, container.Resources.Requests)
+		maxResources(limits, container.Resources.Limits)
+	}
+
+	if pod.Spec.Overhead != nil {
+		addResources(requests, pod.Spec.Overhead)
+		// Overhead only counts towards the limits that are set
+		for name, quantity := range pod.Spec.Overhead {
+			if value, ok := limits[name]; ok {
+				value.Add(quantity)
+				limits[name] = value
+			}
+		}
+	}
+
+	return requests, limits
+}
+
+// nodeAllocations sums the requests and limits of the running pods per node name
+// completed pods no longer hold their resources and are skipped
+func nodeAllocations(pods []*core.Pod) map[string]*allocation {
+	allocations := make(map[string]*allocation)
+	for _, pod := range pods {
+		if pod.Spec.NodeName == "" || pod.Status.Phase == core.PodSucceeded || pod.Status.Phase == core.PodFailed {
+			continue
+		}
+
+		alloc, ok := allocations[pod.Spec.NodeName]
+		if !ok {
+			alloc = &allocation{requests: core.ResourceList{}, limits: core.ResourceList{}}
+			allocations[pod.Spec.NodeName] = alloc
+		}
+
+		requests, limits := podRequestsAndLimits(pod)
+		addResources(alloc.requests, requests)
+		addResources(alloc.limits, limits)
+	}
+	return allocations
+}
+
+// setAllocation fills the requested and limit fields of the node
+// percentages are against the allocatable resources, limits above 100% mean the node is overcommitted
+func setAllocation(nodestats *Node, node *core.Node, alloc *allocation) {
+	// Nodes without any pods have nothing allocated
+	if alloc == nil {
+		alloc = &allocation{}
+	}
+
+	// cpu in millicores and memory in Ki to match the usage fields
+	nodestats.Request_cpu = float32(alloc.requests.Cpu().MilliValue())
+	nodestats.Limit_cpu = float32(alloc.limits.Cpu().MilliValue())
+	nodestats.Request_memory = kibibytes(alloc.requests.Memory())
+	nodestats.Limit_memory = kibibytes(alloc.limits.Memory())
+
+	nodestats.Request_cpu_percent = 0
+	nodestats.Limit_cpu_percent = 0
+	if allocatable := node.Status.Allocatable.Cpu().MilliValue(); allocatable > 0 {
+		nodestats.Request_cpu_percent = nodestats.Request_cpu / float32(allocatable) * 100
+		nodestats.Limit_cpu_percent = nodestats.Limit_cpu / float32(allocatable) * 100
+	}
+
+	nodestats.Request_memory_percent = 0
+	nodestats.Limit_memory_percent = 0
+	if allocatable := kibibytes(node.Status.Allocatable.Memory()); allocatable > 0 {
+		nodestats.Request_memory_percent = float32(nodestats.Request_memory) / float32(allocatable) * 100
+		nodestats.Limit_memory_percent = float32(nodestats.Limit_memory) / float32(allocatable) * 100
+	}
+}
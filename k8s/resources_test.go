@@ -0,0 +1,94 @@
+package k8s
+
+import (
+	"testing"
+
+	core "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/metrics/pkg/apis/metrics/v1beta1"
+)
+
+func TestKibibytes(t *testing.T) {
+	tests := []struct {
+		quantity string
+		want     int
+	}{
+		{"1Ki", 1},
+		{"512Mi", 524288},
+		{"16Gi", 16777216},
+		{"1Ti", 1073741824},
+		{"1Pi", 1099511627776},
+		{"1Ei", 1125899906842624},
+		{"1000k", 976},
+		{"1M", 976},
+		{"1G", 976562},
+		{"1P", 976562500000},
+		{"1E", 976562500000000},
+		{"2048", 2},
+		{"128974848", 125952},
+		{"1e9", 976562},
+		{"7.5Gi", 7864320},
+	}
+
+	for _, test := range tests {
+		q := resource.MustParse(test.quantity)
+		if got := kibibytes(&q); got != test.want {
+			t.Errorf("kibibytes(%s) = %d, want %d", test.quantity, got, test.want)
+		}
+	}
+}
+
+func TestMillicores(t *testing.T) {
+	tests := []struct {
+		quantity string
+		want     float32
+	}{
+		{"2", 2000},
+		{"0.5", 500},
+		{"3500m", 3500},
+		{"250u", 0.25},
+		{"100n", 0.0001},
+		{"1e3", 1000000},
+	}
+
+	for _, test := range tests {
+		q := resource.MustParse(test.quantity)
+		if got := millicores(&q); got != test.want {
+			t.Errorf("millicores(%s) = %v, want %v", test.quantity, got, test.want)
+		}
+	}
+}
+
+// TestGetMetricsForNodeFractional checks the usage of nodes with fractional cpu and memory quantities
+func TestGetMetricsForNodeFractional(t *testing.T) {
+	tests := []struct {
+		capacityCpu, capacityMemory string
+		usageCpu, usageMemory       string
+		wantFreeCpu                 float32
+		wantFreeMemory              int
+	}{
+		{"3500m", "7.5Gi", "1750000000n", "3932160Ki", 1750, 3932160},
+		{"500m", "1536Mi", "250m", "768Mi", 250, 786432},
+	}
+
+	for _, test := range tests {
+		node := &core.Node{Status: core.NodeStatus{Capacity: core.ResourceList{
+			core.ResourceCPU:    resource.MustParse(test.capacityCpu),
+			core.ResourceMemory: resource.MustParse(test.capacityMemory),
+		}}}
+		nm := &v1beta1.NodeMetrics{Usage: core.ResourceList{
+			core.ResourceCPU:    resource.MustParse(test.usageCpu),
+			core.ResourceMemory: resource.MustParse(test.usageMemory),
+		}}
+
+		cpu := GetMetricsForNode(&Node{}, node, nm, "cpu", "capacity", nil)[0]
+		if cpu.Free_cpu != test.wantFreeCpu || cpu.Usage_cpu_percent != 50 {
+			t.Errorf("cpu of %s: free %v and usage %v%%, want %v and 50%%", test.capacityCpu, cpu.Free_cpu, cpu.Usage_cpu_percent, test.wantFreeCpu)
+		}
+
+		memory := GetMetricsForNode(&Node{}, node, nm, "memory", "capacity", nil)[0]
+		if memory.Free_memory != test.wantFreeMemory || memory.Usage_memory_percent != 50 {
+			t.Errorf("memory of %s: free %v and usage %v%%, want %v and 50%%", test.capacityMemory, memory.Free_memory, memory.Usage_memory_percent, test.wantFreeMemory)
+		}
+	}
+}
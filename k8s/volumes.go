@@ -0,0 +1,153 @@
+package k8s
+
+import (
+	"fmt"
+
+	"github.com/AKSarav/KubeNodeUsage/v3/utils"
+
+	core "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// Volume holds the usage of a single pod volume, the json names carry the unit of every value
+type Volume struct {
+	Name                 string            `json:"name"`
+	Pod                  string            `json:"pod"`
+	Namespace            string            `json:"namespace"`
+	NodeName             string            `json:"node"`
+	Type                 string            `json:"type"`
+	PVC                  string            `json:"pvc,omitempty"`
+	Capacity_volume      int               `json:"capacity_volume_bytes"`
+	Usage_volume         int               `json:"usage_volume_bytes"`
+	Free_volume          int               `json:"free_volume_bytes"`
+	Usage_volume_percent float32           `json:"usage_volume_percent"`
+	LabelToDisplay       string            `json:"label,omitempty"`
+	Labels               map[string]string `json:"labels"` // of the pod
+}
+
+// volumeType returns the kind of the volume as shown in the Type column
+// secrets, config maps and the like are tiny tmpfs mounts and return empty to be left out
+func volumeType(volume core.Volume) string {
+	switch {
+	case volume.PersistentVolumeClaim != nil:
+		return "pvc"
+	case volume.Ephemeral != nil:
+		return "ephemeral"
+	case volume.EmptyDir != nil:
+		return "emptyDir"
+	case volume.HostPath != nil:
+		return "hostPath"
+	case volume.CSI != nil:
+		return "csi"
+	case volume.Secret != nil, volume.ConfigMap != nil, volume.Projected != nil, volume.DownwardAPI != nil:
+		return ""
+	}
+	return "other"
+}
+
+// Volumes collects the usage of the pod volumes from the kubelet summary of every node running pods
+func (c *Collector) Volumes() (VolumeStatsList []Volume, err error) {
+	inputs := c.Inputs
+
+	// The label filter is for the labels of the pods and left to the API server
+	selector, err := LabelSelector(inputs.FilterLabel)
+	if err != nil {
+		return nil, err
+	}
+
+	// To fetch kubectl get pods information
+	pods, err := c.scopedPods(inputs, "", selector)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pods: %v", err)
+	}
+
+	// To fetch the nodes the pods run on
+	nodes, err := c.listNodes(labels.Everything())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get nodes: %v", err)
+	}
+
+	podMap := make(map[string]*core.Pod)
+	hosting := make(map[string]bool)
+	for _, pod := range pods {
+		podMap[pod.Namespace+"/"+pod.Name] = pod
+		hosting[pod.Spec.NodeName] = true
+	}
+
+	var hosts []*core.Node
+	for _, node := range nodes {
+		if hosting[node.Name] {
+			hosts = append(hosts, node)
+		}
+	}
+
+	// The volume usage is only reported by the kubelet summary
+	summaries := c.kubeletSummaries(hosts, false)
+
+	for nodeName, summary := range summaries {
+		if summary.stats == nil {
+			utils.Logger.Debug("No kubelet stats for node ", nodeName, ": ", summary.err)
+			continue
+		}
+
+		for _, podStats := range summary.stats.Pods {
+			pod, ok := podMap[podStats.PodRef.Namespace+"/"+podStats.PodRef.Name]
+			if !ok {
+				continue
+			}
+
+			specVolumes := make(map[string]core.Volume)
+			for _, volume := range pod.Spec.Volumes {
+				specVolumes[volume.Name] = volume
+			}
+
+			for _, vs := range podStats.VolumeStats {
+				volume, ok := specVolumes[vs.Name]
+				if !ok {
+					continue
+				}
+				kind := volumeType(volume)
+				if kind == "" {
+					continue
+				}
+
+				volumestats := Volume{
+					Name:            vs.Name,
+					Pod:             pod.Name,
+					Namespace:       pod.Namespace,
+					NodeName:        nodeName,
+					Type:            kind,
+					Capacity_volume: int(vs.CapacityBytes),
+					Usage_volume:    int(vs.UsedBytes),
+					Free_volume:     int(vs.AvailableBytes),
+					Labels:          pod.Labels,
+				}
+
+				// Claim of a pvc or of a generic ephemeral volume
+				if vs.PVCRef != nil {
+					volumestats.PVC = vs.PVCRef.Name
+				} else if volume.PersistentVolumeClaim != nil {
+					volumestats.PVC = volume.PersistentVolumeClaim.ClaimName
+				}
+
+				if vs.CapacityBytes > 0 {
+					volumestats.Usage_volume_percent = float32(vs.UsedBytes) / float32(vs.CapacityBytes) * 100
+				}
+
+				// Display Label if provided
+				if inputs.LabelToDisplay != "" {
+					if _, ok := pod.Labels[inputs.LabelToDisplay]; !ok {
+						volumestats.LabelToDisplay = "NA"
+					} else {
+						volumestats.LabelToDisplay = pod.Labels[inputs.LabelToDisplay]
+					}
+				}
+
+				VolumeStatsList = append(VolumeStatsList, volumestats)
+			}
+		}
+	}
+
+	utils.Logger.Debug(VolumeStatsList)
+	return VolumeStatsList, nil
+}
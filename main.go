@@ -1,14 +1,19 @@
 package main
 
 import (
+	"errors"
 	"flag"
 	"fmt"
 	"os"
 	"reflect"
+	"regexp"
 	"strings"
+	"time"
 
-	"github.com/AKSarav/KubeNodeUsage/v3/cmd/nodemodel"
-	"github.com/AKSarav/KubeNodeUsage/v3/cmd/podmodel"
+	"github.com/AKSarav/KubeNodeUsage/v3/cmd/app"
+	"github.com/AKSarav/KubeNodeUsage/v3/cmd/exporter"
+	"github.com/AKSarav/KubeNodeUsage/v3/cmd/output"
+	"github.com/AKSarav/KubeNodeUsage/v3/k8s"
 	"github.com/AKSarav/KubeNodeUsage/v3/utils"
 
 	"github.com/iancoleman/strcase"
@@ -30,13 +35,27 @@ func usage() {
 	fmt.Printf(displayfmt, "  --sortby", utils.PrintValidSorts())
 	fmt.Printf(displayfmt, "  --filternodes", "filter based on node name")
 	fmt.Printf(displayfmt, "  --filtercolor", "filter based on color category <30 Green, >30 <70 Orange, >70 Red")
-	fmt.Printf(displayfmt, "  --filterlabel", "filter based on labels input should be key value pair in labelkey=labelvalue format")
+	fmt.Printf(displayfmt, "  --filterlabel", "filter based on labels with a kubernetes label selector e.g. pool=gpu, tier!=web, 'env in (prod,staging)', !spot - comma separated requirements all apply")
 	fmt.Printf(displayfmt, "  --desc", "to enable reverse sort")
 	fmt.Printf(displayfmt, "  --debug", "enable debug mode")
 	fmt.Printf(displayfmt, "  --metrics", utils.PrintValidMetrics())
 	fmt.Printf(displayfmt, "  --label", "choose which label to display - syntax is labelname#alias here alias represents the column name to show in the output")
 	fmt.Printf(displayfmt, "  --noinfo", "disable printing of cluster info")
 	fmt.Printf(displayfmt, "  --pods", "show pod usage instead of node usage")
+	fmt.Printf(displayfmt, "  --volumes", "show the usage of the pod volumes and persistent volume claims instead of node usage")
+	fmt.Printf(displayfmt, "  --by", "sum up the pod usage, requests and limits per namespace with their share of the cluster - "+utils.PrintValidBys())
+	fmt.Printf(displayfmt, "  --basis", "calculate Free and Usage% against the node capacity or allocatable - "+utils.PrintValidBases())
+	fmt.Printf(displayfmt, "  --bandwidth", "link speed of the nodes in Mbit/s the network usage percent is calculated against - default 1000")
+	fmt.Printf(displayfmt, "  --diskdetail", "split the node disk usage into rootfs, imagefs and logs with the inode usage - needs --metrics disk")
+	fmt.Printf(displayfmt, "  --kubeconfig", "path to the kubeconfig file - defaults to KUBECONFIG env or ~/.kube/config")
+	fmt.Printf(displayfmt, "  --context", "kubeconfig context to use - defaults to the current context")
+	fmt.Printf(displayfmt, "  --namespace", "list the pods of these comma separated namespaces only")
+	fmt.Printf(displayfmt, "  --all-namespaces", "list the pods of all namespaces - the default without --namespace")
+	fmt.Printf(displayfmt, "  --exclude-namespace", "leave the pods of these comma separated namespaces out e.g. kube-system")
+	fmt.Printf(displayfmt, "  --output", "print once and exit without the TUI - "+utils.PrintValidOutputs())
+	fmt.Printf(displayfmt, "  --exporter", "serve the usage as prometheus metrics on the given address e.g. :9100 instead of starting the TUI")
+	fmt.Printf(displayfmt, "  --interval", "refresh interval of the views e.g. 5s - defaults to 1s for nodes, 5s for pods and 10s for volumes")
+	fmt.Printf(displayfmt, "  --watch", "watch nodes and pods with informers and only poll metrics on refresh - needs list/watch permission")
 	os.Exit(1)
 }
 
@@ -73,14 +92,94 @@ func checkinputs(args *utils.Inputs) {
 		usage()
 	}
 
+	// Volumes are a view of their own
+	if args.Volumes && args.Pods {
+		utils.Logger.Error("Only one of --pods and --volumes can be used")
+		usage()
+	}
+
+	// The combined view is only available for nodes
+	if args.Pods && args.Metrics == "all" {
+		utils.Logger.Error("Metric all is only supported for nodes")
+		usage()
+	}
+
+	// Sort keys addressed to a metric need that metric to be displayed
+	// the combined view shows cpu, memory and disk
+	sortMetric, sortKey := utils.SortMetric(args.SortBy, args.Metrics)
+	if args.Metrics != "all" && sortMetric != args.Metrics || args.Metrics == "all" && sortMetric == "network" {
+		utils.Logger.Error("Invalid sort for metric ", args.Metrics, ": ", args.SortBy)
+		usage()
+	}
+
+	// Requests and limits are only set for memory and cpu, volumes have none
+	if (sortKey == "request" || sortKey == "limit") && (sortMetric != "memory" && sortMetric != "cpu" || args.Volumes) {
+		utils.Logger.Error("Sort ", sortKey, " is only supported for memory and cpu: ", args.SortBy)
+		usage()
+	}
+
+	// The received and transmitted rates are only collected for the network, which has no allocatable
+	if (sortKey == "rx" || sortKey == "tx") && sortMetric != "network" {
+		utils.Logger.Error("Sort ", sortKey, " is only supported for network: ", args.SortBy)
+		usage()
+	}
+	if sortKey == "allocatable" && sortMetric == "network" {
+		utils.Logger.Error("Sort allocatable is not supported for network: ", args.SortBy)
+		usage()
+	}
+
+	// The network usage percent needs the link speed of the nodes
+	if args.Metrics == "network" && args.Bandwidth <= 0 {
+		utils.Logger.Error("Invalid bandwidth: ", args.Bandwidth)
+		usage()
+	}
+
+	// The pods are summed up per namespace for memory and cpu
+	if args.By != "" {
+		checkBy(args)
+	} else if args.SortBy == "pods" {
+		utils.Logger.Error("Sort pods is only supported with --by namespace")
+		usage()
+	}
+
+	// Disk detail is a breakdown of the node disk usage
+	if args.DiskDetail && (args.Metrics != "disk" || args.Pods) {
+		utils.Logger.Error("Disk detail is only supported for nodes with --metrics disk")
+		usage()
+	}
+
+	// The views refresh at least every second
+	if args.Interval != 0 && args.Interval < time.Second {
+		utils.Logger.Error("Invalid interval: ", args.Interval, ", it must be at least 1s")
+		usage()
+	}
+
+	// The pods come either from the namespaces given or from all of them
+	if args.AllNamespaces && args.Namespace != "" {
+		utils.Logger.Error("Only one of --namespace and --all-namespaces can be used")
+		usage()
+	}
+
+	// Check if basis is valid
+	if !utils.IsValidBasis(args.Basis) {
+		utils.Logger.Error("Invalid basis: ", args.Basis)
+		usage()
+	}
+
+	// Check if output is valid
+	if args.Output != "" && !utils.IsValidOutput(args.Output) {
+		utils.Logger.Error("Invalid output: ", args.Output)
+		usage()
+	}
+
 	// Check if filtercolor is valid
 	if args.FilterColor != "" && !utils.IsValidColor(args.FilterColor) {
 		utils.Logger.Error("Invalid color: ", args.FilterColor)
 		usage()
 	}
 
-	// Check if all filters are on
-	IsAllFiltersOn(args)
+	// Check the filters, they can be combined and apply together
+	checkFilters(args)
 
 	// Set log level
 	if args.Debug {
@@ -98,10 +197,40 @@ func checkinputs(args *utils.Inputs) {
 	}
 }
 
-func IsAllFiltersOn(args *utils.Inputs) {
-	if args.FilterLabel != "" && args.FilterNodes != "" && args.FilterColor != "" {
-		utils.Logger.Error("Only one filter can be used at a time")
-		os.Exit(2)
+// checkBy validates --by and what can be combined with it
+func checkBy(args *utils.Inputs) {
+	if !utils.IsValidBy(args.By) {
+		utils.Logger.Error("Invalid by: ", args.By)
+		usage()
+	}
+	if args.Volumes || args.Exporter != "" {
+		utils.Logger.Error("--by can not be used with --volumes or --exporter")
+		usage()
+	}
+	if args.Metrics != "memory" && args.Metrics != "cpu" {
+		utils.Logger.Error("Metric ", args.Metrics, " is not supported with --by, use memory or cpu")
+		usage()
+	}
+	if _, key := utils.SortMetric(args.SortBy, args.Metrics); !utils.Contains([]string{"", "name", "namespace", "pods", "usage", "color", "request", "limit"}, key) {
+		utils.Logger.Error("Invalid sort for --by ", args.By, ": ", args.SortBy)
+		usage()
+	}
+}
+
+// checkFilters validates the regular expressions of --filternodes and the label selector of --filterlabel
+func checkFilters(args *utils.Inputs) {
+	if args.FilterNodes != "" {
+		for _, pattern := range strings.Split(args.FilterNodes, ",") {
+			if _, err := regexp.Compile(pattern); err != nil {
+				utils.Logger.Error("Invalid filternodes pattern ", pattern, ": ", err)
+				usage()
+			}
+		}
+	}
+
+	if _, err := k8s.LabelSelector(args.FilterLabel); err != nil {
+		utils.Logger.Error("Invalid filterlabel: ", err)
+		usage()
 	}
 }
 
@@ -122,7 +251,21 @@ func main() {
 	flag.BoolVar(&args.Debug, "debug", false, "Debug mode")
 	flag.BoolVar(&args.NoInfo, "noinfo", false, "No info")
 	flag.BoolVar(&args.Pods, "pods", false, "Show pods")
+	flag.BoolVar(&args.Volumes, "volumes", false, "Show volumes")
+	flag.StringVar(&args.By, "by", "", "Sum up the pods by namespace")
+	flag.StringVar(&args.Basis, "basis", "capacity", "Capacity or allocatable as the basis for percentages")
+	flag.BoolVar(&args.DiskDetail, "diskdetail", false, "Node disk usage breakdown")
+	flag.IntVar(&args.Bandwidth, "bandwidth", 1000, "Link speed of the nodes in Mbit/s")
 	flag.BoolVar(&args.Help, "help", false, "Help")
+	flag.StringVar(&args.Kubeconfig, "kubeconfig", "", "Path to the kubeconfig file")
+	flag.StringVar(&args.Context, "context", "", "Kubeconfig context to use")
+	flag.StringVar(&args.Namespace, "namespace", "", "Namespaces to list the pods from")
+	flag.BoolVar(&args.AllNamespaces, "all-namespaces", false, "List the pods of all namespaces")
+	flag.StringVar(&args.ExcludeNamespaces, "exclude-namespace", "", "Namespaces to leave out")
+	flag.BoolVar(&args.Watch, "watch", false, "Watch nodes and pods with informers")
+	flag.DurationVar(&args.Interval, "interval", 0, "Refresh interval of the views")
+	flag.StringVar(&args.Output, "output", "", "Output format for one-shot mode")
+	flag.StringVar(&args.Exporter, "exporter", "", "Address to serve prometheus metrics on")
 	flag.Parse()
 
 	// Check inputs
@@ -131,13 +274,44 @@ func main() {
 	// Print args if debug is enabled
 	PrintArgs(args)
 
-	// Initialize the appropriate model based on the --pods flag
-	var mdl tea.Model
-	if args.Pods {
-		mdl = podmodel.NewPodUsage(&args)
-	} else {
-		mdl = nodemodel.NewNodeUsage(&args)
+	// Build the kubernetes clients once for the whole session
+	collector, err := k8s.NewCollector(&args)
+	if err != nil {
+		var connErr *k8s.ConnectionError
+		if errors.As(err, &connErr) {
+			fmt.Println("\n# ERROR: Unable to Establish Connection to Kubernetes Cluster")
+			fmt.Println("# Kubernetes Context:", connErr.Cluster.Context)
+			fmt.Println("# Kubernetes URL:", connErr.Cluster.URL)
+			fmt.Println("# Please check your kubernetes configuration and permissions")
+		} else {
+			fmt.Println("Error:", err)
+		}
+		os.Exit(2)
 	}
+	defer collector.Close()
+
+	// Exporter mode - serve the usage as prometheus metrics until stopped
+	if args.Exporter != "" {
+		if err := exporter.Run(&args, collector); err != nil {
+			collector.Close()
+			fmt.Fprintln(os.Stderr, "Error:", err)
+			os.Exit(2)
+		}
+		return
+	}
+
+	// One-shot output mode - print the rows and exit without starting the TUI
+	if args.Output != "" {
+		if err := output.Run(&args, collector); err != nil {
+			collector.Close()
+			fmt.Fprintln(os.Stderr, "Error:", err)
+			os.Exit(2)
+		}
+		return
+	}
+
+	// The app starts with the view chosen by the --pods and --volumes flags
+	mdl := app.New(&args, collector)
 
 	// Run the program
 	p := tea.NewProgram(mdl)
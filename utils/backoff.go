@@ -0,0 +1,19 @@
+package utils
+
+import "time"
+
+// MaxBackoff caps the delay between retries when the refresh keeps failing
+var MaxBackoff = 60 * time.Second
+
+// Backoff returns the delay before the next refresh
+// the interval is doubled for every consecutive failure up to MaxBackoff
+func Backoff(interval time.Duration, failures int) time.Duration {
+	delay := interval
+	for i := 0; i < failures && delay < MaxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > MaxBackoff {
+		delay = MaxBackoff
+	}
+	return delay
+}
@@ -1,19 +1,96 @@
 package utils
 
+import (
+	"strings"
+	"time"
+)
+
 type Inputs struct {
-	HelpFlag       bool
-	ReverseFlag    bool
-	Debug          bool
-	SortBy         string
-	FilterNodes    string
-	FilterColor    string
-	FilterLabel    string
-	Metrics        string
-	LabelToDisplay string
-	LabelAlias     string
-	NoInfo         bool
-	Pods           bool
-	Help           bool
+	HelpFlag          bool
+	ReverseFlag       bool
+	Debug             bool
+	SortBy            string
+	FilterNodes       string
+	FilterColor       string
+	FilterLabel       string
+	Metrics           string
+	LabelToDisplay    string
+	LabelAlias        string
+	NoInfo            bool
+	Pods              bool
+	Volumes           bool
+	By                string // what the pods are summed up by instead of shown one by one, like namespace
+	Help              bool
+	Kubeconfig        string
+	Context           string
+	Namespace         string // comma separated namespaces the pods are listed from, a single one is the kubeconfig override too
+	AllNamespaces     bool
+	ExcludeNamespaces string // comma separated namespaces left out of the pods listed
+	Watch             bool
+	Output            string
+	Exporter          string
+	Basis             string
+	DiskDetail        bool
+	Bandwidth         int           // Mbit/s of the node links for the network usage percent
+	Interval          time.Duration // between the refreshes of the views, zero for the default of every view
 }
 
 var HeaderLines = 14
+
+// BodyStart returns the index of the first row below the dashed line under the table header
+// HeaderLines is the fallback for content without one
+func BodyStart(lines []string) int {
+	for i, line := range lines {
+		if strings.HasPrefix(line, "---") {
+			return i + 1
+		}
+	}
+	if HeaderLines > len(lines) {
+		return len(lines)
+	}
+	return HeaderLines
+}
+
+// Next returns the item after current in the list, wrapping around at the end
+// the first item when current is not in the list
+func Next(list []string, current string) string {
+	for i, item := range list {
+		if item == current {
+			return list[(i+1)%len(list)]
+		}
+	}
+	return list[0]
+}
+
+// Contains tells if the item is in the list
+func Contains(list []string, item string) bool {
+	for _, each := range list {
+		if each == item {
+			return true
+		}
+	}
+	return false
+}
+
+// SortIndicator describes the sort in the view headers, no sort key is a sort by name
+func SortIndicator(sortBy string, reverse bool) string {
+	if sortBy == "" {
+		sortBy = "name"
+	}
+	if reverse {
+		return sortBy + " ↓ (descending)"
+	}
+	return sortBy + " ↑ (ascending)"
+}
+
+// RefreshIndicator describes when the data on screen was fetched and how it refreshes for the view headers
+func RefreshIndicator(updated time.Time, interval time.Duration, paused bool) string {
+	at := "never"
+	if !updated.IsZero() {
+		at = updated.Format("15:04:05")
+	}
+	if paused {
+		return at + " - paused, R to refresh"
+	}
+	return at + " - refreshing every " + interval.String()
+}
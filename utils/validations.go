@@ -9,19 +9,45 @@ var ValidColors = map[string]bool{
 }
 
 var ValidMetrics = map[string]bool{
-	"memory": true,
-	"disk":   true,
-	"cpu":    true,
+	"memory":  true,
+	"disk":    true,
+	"cpu":     true,
+	"network": true,
+	"all":     true,
+}
+
+var ValidBases = map[string]bool{
+	"capacity":    true,
+	"allocatable": true,
+}
+
+var ValidOutputs = map[string]bool{
+	"json":  true,
+	"yaml":  true,
+	"csv":   true,
+	"wide":  true,
+	"table": true,
+}
+
+// ValidBys are what the pods can be summed up by with --by
+var ValidBys = map[string]bool{
+	"namespace": true,
 }
 
 var ValidSorts = map[string]bool{
-	"name":     true,
-	"node":     true,
-	"free":     true,
-	"usage":    true,
-	"color":    true,
-	"capacity": true,
-	"max":      true,
+	"name":        true,
+	"node":        true,
+	"free":        true,
+	"usage":       true,
+	"color":       true,
+	"capacity":    true,
+	"max":         true,
+	"request":     true,
+	"limit":       true,
+	"pods":        true,
+	"allocatable": true,
+	"rx":          true,
+	"tx":          true,
 }
 
 func IsValidColor(input string) bool {
@@ -29,16 +55,51 @@ func IsValidColor(input string) bool {
 	return match // if matched true else false
 }
 
+// IsValidSort accepts the plain sort keys and the ones addressed to a metric like cpu.usage
+// a metric is given once, the key after it is a plain one
 func IsValidSort(input string) bool {
-	_, match := ValidSorts[input]
+	metric, key, found := strings.Cut(input, ".")
+	if !found {
+		key = input
+	} else if metric == "all" || !IsValidMetric(metric) {
+		return false
+	}
+	_, match := ValidSorts[key]
 	return match // if matched true else false
 }
 
+// SortMetric splits a sort key like cpu.usage into its metric and key
+// plain keys like usage apply to the displayed metric, memory in the combined view
+func SortMetric(sortBy string, metric string) (string, string) {
+	if before, after, found := strings.Cut(sortBy, "."); found {
+		return before, after
+	}
+	if metric == "all" {
+		return "memory", sortBy
+	}
+	return metric, sortBy
+}
+
 func IsValidMetric(input string) bool {
 	_, match := ValidMetrics[input]
 	return match // if matched true else false
 }
 
+func IsValidBasis(input string) bool {
+	_, match := ValidBases[input]
+	return match // if matched true else false
+}
+
+func IsValidOutput(input string) bool {
+	_, match := ValidOutputs[input]
+	return match // if matched true else false
+}
+
+func IsValidBy(input string) bool {
+	_, match := ValidBys[input]
+	return match // if matched true else false
+}
+
 func PrintValidColors() []string {
 	var result []string
 	for k := range ValidColors {
@@ -52,7 +113,7 @@ func PrintValidMetrics() string {
 	for k := range ValidMetrics {
 		result = append(result, k)
 	}
-	return "Choose one of ["+strings.Join(result, ", ")+"]"
+	return "Choose one of [" + strings.Join(result, ", ") + "]"
 }
 
 func PrintValidSorts() string {
@@ -61,5 +122,29 @@ func PrintValidSorts() string {
 		result = append(result, k)
 	}
 	// return comma separated string
-	return "Choose one of ["+strings.Join(result, ", ")+"]"
+	return "Choose one of [" + strings.Join(result, ", ") + "] - prefix with cpu. memory. or disk. to sort by a specific metric in the all view"
+}
+
+func PrintValidOutputs() string {
+	var result []string
+	for k := range ValidOutputs {
+		result = append(result, k)
+	}
+	return "Choose one of [" + strings.Join(result, ", ") + "]"
+}
+
+func PrintValidBases() string {
+	var result []string
+	for k := range ValidBases {
+		result = append(result, k)
+	}
+	return "Choose one of [" + strings.Join(result, ", ") + "]"
+}
+
+func PrintValidBys() string {
+	var result []string
+	for k := range ValidBys {
+		result = append(result, k)
+	}
+	return "Choose one of [" + strings.Join(result, ", ") + "]"
 }